@@ -2,11 +2,16 @@ package main
 
 import (
 	"./binpuz"
+	"./binpuz/render"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 )
 
 const maxcount = 5
@@ -14,21 +19,91 @@ const maxcount = 5
 var soln = flag.Bool("solution", false, "Show solution")
 var verb = flag.Bool("working", false, "Shows working out")
 var diff = flag.Bool("difficulty", false, "Information on difficulty")
+var coll = flag.Bool("collection", false, "Read a puzzle collection and check every puzzle in it")
+var code = flag.Bool("code", false, "Show the puzzle as a single line code")
+var format = flag.String("format", "", "Read the puzzle in this format ("+strings.Join(binpuz.Formats(), ", ")+"), instead of text or a code")
+var to = flag.String("to", "", "Also show the puzzle in this format")
+var anim = flag.String("gif", "", "Write an animated GIF of the working out to this file")
+var replay = flag.Bool("replay", false, "Replay the working out step by step in the terminal")
+var delay = flag.Duration("delay", time.Second, "Delay between steps for -replay")
+var workers = flag.Int("workers", 1, "Number of goroutines counting solutions")
+var image = flag.String("image", "", "Draw the puzzle into this .svg or .png file (and its solution, with -solution)")
+var backend = flag.String("backend", "backtrack", "Find solutions by backtrack, sat or patterns")
+var dimacs = flag.String("dimacs", "", "Write the puzzle as CNF to this file, in DIMACS format")
+var color = flag.String("color", "auto", "Draw boards with colors and grid lines: always, never, or auto (when writing to a terminal)")
+
+// term draws boards on stdout, as chosen by -color.
+var term render.Terminal
 
 func main() {
 	flag.Parse()
+	binpuz.DefaultSearch.Workers = *workers
+	backends := map[string]binpuz.Backend{"backtrack": binpuz.Backtrack, "sat": binpuz.SAT, "patterns": binpuz.Patterns}
+	if k, ok := backends[*backend]; ok {
+		binpuz.DefaultSearch.Backend = k
+	} else {
+		fmt.Println("-backend must be backtrack, sat or patterns")
+		return
+	}
 
-	puzz, err := ioutil.ReadAll(os.Stdin)
-	if err != nil {
-		panic(err)
+	switch *color {
+	case "always":
+		term.Fancy = true
+	case "never":
+	case "auto":
+		term = render.NewTerminal(os.Stdout)
+	default:
+		fmt.Println("-color must be always, never or auto")
+		return
 	}
-	puzzs := strings.TrimSpace(string(puzz))
-	p, err := binpuz.FromString(puzzs)
-	if err != nil {
+
+	if *coll {
+		examineCollection()
+		return
+	}
+
+	// The puzzle is either given as an argument (usually a code), or on stdin.
+	var puzzs string
+	if flag.NArg() > 0 {
+		puzzs = strings.Join(flag.Args(), "\n")
+	} else {
+		puzz, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			panic(err)
+		}
+		puzzs = strings.TrimSpace(string(puzz))
+	}
+	read := binpuz.FromString
+	if *format != "" {
+		f, err := binpuz.LookupFormat(*format)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		read = f.Read
+	}
+	p, err := read(puzzs)
+	var cv *binpuz.ConstraintViolation
+	if errors.As(err, &cv) {
+		printConflicts(p)
+		return
+	} else if err != nil {
 		fmt.Println(err)
 		return
 	}
 
+	printFormats(p)
+	if *image != "" {
+		if err := render.WriteFile(*image, p, nil); err != nil {
+			fmt.Println(err)
+		}
+	}
+	if *dimacs != "" {
+		if err := writeDIMACS(*dimacs, p); err != nil {
+			fmt.Println(err)
+		}
+	}
+
 	nsolns := p.CountSolns(maxcount)
 	fmt.Printf("Counted %d solutions (up to a maximum of %d)\n", nsolns, maxcount)
 	if nsolns != 1 {
@@ -36,21 +111,39 @@ func main() {
 	}
 	if *soln {
 		fmt.Println("Puzzle:")
-		fmt.Println(p)
+		fmt.Println(term.Board(p, nil))
 		fmt.Println("Solution:")
 		solns := p.ListSolns()
-		fmt.Println(solns[0])
+		fmt.Println(term.Board(solns[0], &render.Options{Puzzle: p}))
+		if *image != "" {
+			ext := filepath.Ext(*image)
+			name := strings.TrimSuffix(*image, ext) + "-solution" + ext
+			if err := render.WriteFile(name, solns[0], &render.Options{Puzzle: p}); err != nil {
+				fmt.Println(err)
+			}
+		}
 	}
 
 	s, steps, _ := p.Solve()
+	if *anim != "" {
+		if err := writeGIF(*anim, p, steps); err != nil {
+			fmt.Println(err)
+		}
+	}
+	if *replay {
+		if err := render.Replay(os.Stdout, p, steps, *delay); err != nil {
+			fmt.Println(err)
+		}
+	}
 	if *verb {
 		fmt.Printf("\n\nHow to solve:\n\n")
-		fmt.Println(p)
+		fmt.Println(term.Board(p, nil))
 
-		p := p.Clone()
+		b := p.Clone()
 		for _, step := range steps {
-			p.Apply(step.Changes)
-			fmt.Printf("\n%s (Difficulty %d)\n%v\n", step.Reason, step.Diff, p)
+			b.Apply(step.Changes)
+			board := term.Board(b, &render.Options{Puzzle: p, Highlight: step.Changes})
+			fmt.Printf("\n%s (Difficulty %d)\n%s\n", step.Reason, step.Diff, board)
 		}
 	}
 
@@ -84,3 +177,108 @@ func main() {
 		}
 	}
 }
+
+// printConflicts lists everything wrong with an invalid puzzle.
+func printConflicts(p binpuz.Board) {
+	conflicts := p.Conflicts()
+	fmt.Printf("Puzzle breaks the rules in %d places:\n", len(conflicts))
+	for _, cv := range conflicts {
+		fmt.Printf("  %v:", cv)
+		for _, c := range cv.Cells {
+			fmt.Printf(" (%d, %d)", c.I+1, c.J+1)
+		}
+		fmt.Println()
+	}
+	var cells []binpuz.Change
+	for _, cv := range conflicts {
+		cells = append(cells, cv.Cells...)
+	}
+	fmt.Println(term.Board(p, &render.Options{Highlight: cells}))
+}
+
+// printFormats shows the puzzle as a code and in the format given by -to, if
+// asked for.
+func printFormats(p binpuz.Board) {
+	if *code {
+		c, err := p.Code()
+		if err != nil {
+			fmt.Println(err)
+		} else {
+			fmt.Println("Code:", c)
+		}
+	}
+	if *to != "" {
+		f, err := binpuz.LookupFormat(*to)
+		if err == nil {
+			var s string
+			if s, err = f.Write(p); err == nil {
+				fmt.Printf("As %s:\n%s\n", f.Name, s)
+			}
+		}
+		if err != nil {
+			fmt.Println(err)
+		}
+	}
+}
+
+// writeGIF writes an animation of the puzzle being solved to the named file.
+func writeGIF(name string, p binpuz.Board, steps []binpuz.Step) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := render.GIF(f, p, steps, nil); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func writeDIMACS(name string, p binpuz.Board) error {
+	cnf, err := p.CNF()
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := cnf.WriteDIMACS(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// examineCollection reads a collection from stdin, and prints one line for each
+// puzzle in it: how many solutions it has, and its difficulty. Puzzles which do
+// not match what the collection records about them are flagged.
+func examineCollection() {
+	r := binpuz.NewCollectionReader(os.Stdin)
+	for n := 1; ; n++ {
+		e, err := r.Read()
+		if err == io.EOF {
+			return
+		} else if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		p := e.Puzzle
+		printFormats(p)
+
+		nsolns := p.CountSolns(maxcount)
+		grade, err := p.Grade()
+		if err != nil {
+			grade = -1
+		}
+		fmt.Printf("Puzzle %d (%dx%d, %d numbers): %d solutions, difficulty %d", n, p.Height, p.Width, p.Count(), nsolns, grade)
+		if grade != e.Diff {
+			fmt.Printf(" (MISMATCH: recorded difficulty %d)", e.Diff)
+		}
+		if nsolns == 1 && e.Solution.Height > 0 && p.ListSolns()[0].String() != e.Solution.String() {
+			fmt.Printf(" (MISMATCH: recorded solution differs)")
+		}
+		fmt.Println()
+	}
+}
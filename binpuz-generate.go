@@ -2,100 +2,72 @@ package main
 
 import (
 	"./binpuz"
+	"./binpuz/render"
+	"context"
+	"flag"
 	"fmt"
 	"math/rand"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"sort"
+	"strings"
 	"time"
 )
 
-type Coord struct{ i, j int }
-type Coords []Coord
+var size = flag.Int("size", 10, "Width and height of generated puzzles")
+var odd = flag.Bool("odd", false, "Use the odd-sized rules, allowing odd sizes")
+var pdf = flag.String("pdf", "", "Also write the puzzles as a printable booklet to this PDF file")
+var perPage = flag.Int("per-page", 4, "Number of puzzles on each page of the booklet")
+var timeout = flag.Duration("timeout", 0, "Give up on a puzzle if generating or reducing it takes longer than this (0 for no limit)")
+var images = flag.String("images", "", "Also draw each puzzle into an .svg or .png file named by this pattern, such as puzzle-%03d.png")
 
-func (c Coords) Len() int           { return len(c) }
-func (c Coords) Less(i, j int) bool { return c[i].i < c[j].i || (c[i].i == c[j].i && c[i].j < c[j].j) }
-func (c Coords) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
-
-type Boards []binpuz.Board
-
-func (b Boards) Len() int           { return len(b) }
-func (b Boards) Less(i, j int) bool { return b[i].Count() < b[j].Count() }
-func (b Boards) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
-
-func Shuffle(elems sort.Interface, r *rand.Rand) {
-	len := elems.Len()
-	for i := len - 1; i >= 0; i-- {
-		elems.Swap(i, r.Intn(i+1))
+// rules returns the rule set chosen on the command line.
+func rules() binpuz.RuleSet {
+	if *odd {
+		return binpuz.OddSized
 	}
+	return binpuz.Standard
 }
 
-func CoordsFor(size int) []Coord {
-	coords := make([]Coord, size*size)
-	for i := 0; i < size; i++ {
-		for j := 0; j < size; j++ {
-			coords[i*size+j] = Coord{i, j}
-		}
-	}
-	return coords
-}
+type Entries []binpuz.Entry
 
-func RandByte(r *rand.Rand) byte {
-	if rand.Intn(2) == 0 {
-		return binpuz.Zero
-	}
-	return binpuz.One
-}
+func (e Entries) Len() int           { return len(e) }
+func (e Entries) Less(i, j int) bool { return e[i].Puzzle.Count() < e[j].Puzzle.Count() }
+func (e Entries) Swap(i, j int)      { e[i], e[j] = e[j], e[i] }
 
 // Rate the difficulty of a board. Panics if the board is inconsistent.
 // Returns -1 for unsolved boards.
 func Diff(b binpuz.Board) int {
-	s, steps, err := b.Solve()
+	diff, err := b.Grade()
 	if err != nil {
 		panic(err)
 	}
-	if !s.Solved() {
-		return -1
-	}
-	diff := 0
-	for _, step := range steps {
-		if diff < step.Diff {
-			diff = step.Diff
-		}
-	}
 	return diff
 }
 
+// puzzleContext returns a context bounding the work on one puzzle, as set by
+// -timeout.
+func puzzleContext() (context.Context, context.CancelFunc) {
+	if *timeout > 0 {
+		return context.WithTimeout(context.Background(), *timeout)
+	}
+	return context.WithCancel(context.Background())
+}
+
 // genFull will generate puzzles which have a unique solution, and pass them back on a channel.
-func genFull(r *rand.Rand, out chan<- binpuz.Board) {
-	size := 10
-	coords := CoordsFor(size)
+// Each puzzle is generated from its own seed, drawn from gen, which is passed on with it.
+func genFull(gen *rand.Rand, out chan<- binpuz.Entry) {
 	for {
-		// Build a puzzle by placing things randomly. We might need to backtrack here.
-		board := binpuz.New(size)
-		Shuffle(Coords(coords), r)
-		var f func(idx int) bool
-		f = func(idx int) bool {
-			i, j := coords[idx].i, coords[idx].j
-			b := RandByte(r)
-			board.Set(i, j, b)
-			solns := board.CountSolns(2)
-			if solns == 1 || solns >= 2 && f(idx+1) {
-				return true
-			}
-			if b == binpuz.Zero {
-				b = binpuz.One
-			} else {
-				b = binpuz.Zero
-			}
-			board.Set(i, j, b)
-			if f(idx + 1) {
-				return true
-			}
-			return false
+		seed := gen.Int63()
+		ctx, cancel := puzzleContext()
+		board, err := binpuz.GenerateContext(ctx, *size, *size, rules(), rand.New(rand.NewSource(seed)))
+		cancel()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Gave up generating a puzzle:", err)
+			continue
 		}
-		f(0)
-		out <- board
+		out <- binpuz.Entry{Puzzle: board, Seed: seed}
 	}
 }
 
@@ -104,53 +76,103 @@ const reductions = 10
 // I have a feeling that removing numbers off a board with a unique solution is "strictly decreasing",
 // in that if a number cannot be removed at an earlier step, that same number will not be able to be
 // removed at a later step.
-func reduce(r *rand.Rand, in <-chan binpuz.Board, out chan<- binpuz.Board) {
-	var coords []Coord
-	for {
-		board := <-in
-		m := make(map[int]binpuz.Board)
-		for reds := 0; reds < reductions; reds++ {
-			board := board.Clone()
-			coords = coords[:0]
-			for i := 0; i < board.Size; i++ {
-				for j := 0; j < board.Size; j++ {
-					if board.Get(i, j) != binpuz.Empty {
-						coords = append(coords, Coord{i, j})
-					}
-				}
-			}
-			m[Diff(board)] = board.Clone()
-			Shuffle(Coords(coords), r)
-			for _, coord := range coords {
-				i, j := coord.i, coord.j
-				change := board.Set(i, j, binpuz.Empty)
-				if !board.HasUniqueSoln() {
-					board.Undo(change)
-				} else {
-					m[Diff(board)] = board.Clone()
-				}
-			}
-		}
-		for _, v := range m {
-			out <- v
+//
+// reduceEntry reduces the puzzle of an entry reductions times, and returns the
+// last puzzle visited for each difficulty. All the reductions draw from one
+// random source seeded with the seed of the entry, so the same puzzle and seed
+// always give the same puzzles. It returns nothing if it gives up, as what it
+// would have visited by then depends on the timing.
+func reduceEntry(entry binpuz.Entry) []binpuz.Entry {
+	r := rand.New(rand.NewSource(entry.Seed))
+	m := make(map[int]binpuz.Entry)
+	ctx, cancel := puzzleContext()
+	defer cancel()
+	for reds := 0; reds < reductions; reds++ {
+		_, err := entry.Puzzle.ReduceContext(ctx, r, func(board binpuz.Board) {
+			m[Diff(board)] = binpuz.Entry{Puzzle: board.Clone(), Seed: entry.Seed}
+		})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Gave up reducing a puzzle:", err)
+			return nil
 		}
+	}
+	diffs := make([]int, 0, len(m))
+	for diff := range m {
+		diffs = append(diffs, diff)
+	}
+	sort.Ints(diffs)
+	entries := make([]binpuz.Entry, len(diffs))
+	for k, diff := range diffs {
+		entries[k] = m[diff]
+	}
+	return entries
+}
 
+// reduce reduces the puzzles it is given, and passes back what it finds.
+func reduce(in <-chan binpuz.Entry, out chan<- binpuz.Entry) {
+	for entry := range in {
+		for _, e := range reduceEntry(entry) {
+			out <- e
+		}
 	}
 }
 
 const keep = 5
 
+// contains returns true if one of the entries holds the given puzzle.
+func contains(entries []binpuz.Entry, board binpuz.Board) bool {
+	for _, e := range entries {
+		if e.Puzzle.String() == board.String() {
+			return true
+		}
+	}
+	return false
+}
+
 func main() {
+	flag.Parse()
+	// Fail early (rather than in a goroutine) on bad sizes.
+	if err := rules().CheckSize(*size, *size); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	// A puzzle (usually a code) can be given as an argument, in which case
+	// we only reduce that rather than generating new ones.
+	var start binpuz.Board
+	if flag.NArg() > 0 {
+		var err error
+		start, err = binpuz.FromString(strings.Join(flag.Args(), "\n"))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if !start.HasUniqueSoln() {
+			fmt.Fprintln(os.Stderr, "The puzzle must have a unique solution")
+			os.Exit(1)
+		}
+	}
+
 	seed := time.Now().UTC().UnixNano()
 	getRand := func() *rand.Rand {
 		seed++
 		return rand.New(rand.NewSource(seed))
 	}
-	c := make(chan binpuz.Board, 10)
-	d := make(chan binpuz.Board)
-	for i := 0; i < 4; i++ {
-		go reduce(getRand(), c, d)
-		go genFull(getRand(), c)
+	d := make(chan binpuz.Entry)
+	if start.Height > 0 {
+		// The given puzzle is only reduced once, so we are done after that.
+		go func() {
+			for _, e := range reduceEntry(binpuz.Entry{Puzzle: start, Seed: seed}) {
+				d <- e
+			}
+			close(d)
+		}()
+	} else {
+		c := make(chan binpuz.Entry, 10)
+		for i := 0; i < 4; i++ {
+			go reduce(c, d)
+			go genFull(getRand(), c)
+		}
 	}
 
 	stop := make(chan struct{})
@@ -158,28 +180,36 @@ func main() {
 	signal.Notify(sigs, os.Interrupt)
 	go func() {
 		for range sigs {
-			fmt.Println("got sig")
+			fmt.Fprintln(os.Stderr, "got sig")
 			stop <- struct{}{}
 		}
 	}()
 
-	m := make(map[int][]binpuz.Board)
+	m := make(map[int][]binpuz.Entry)
 	count := 0
 	mod := 1
 loop:
 	for {
 		select {
-		case board := <-d:
-			diff := Diff(board)
-			m[diff] = append(m[diff], board)
+		case entry, ok := <-d:
+			if !ok {
+				break loop
+			}
+			diff := Diff(entry.Puzzle)
+			entry.Diff = diff
+			// Reducing the same puzzle often gives the same results.
+			if contains(m[diff], entry.Puzzle) {
+				continue
+			}
+			m[diff] = append(m[diff], entry)
 
 			if len(m[diff]) > keep {
-				sort.Sort(Boards(m[diff]))
+				sort.Sort(Entries(m[diff]))
 				m[diff] = m[diff][:keep]
 			}
 			count++
 			if count%mod == 0 {
-				fmt.Println(count, "boards collected")
+				fmt.Fprintln(os.Stderr, count, "boards collected")
 			}
 			if count == mod*10 {
 				mod *= 10
@@ -194,13 +224,62 @@ loop:
 		keys = append(keys, k)
 	}
 	sort.Ints(keys)
+	w := binpuz.NewCollectionWriter(os.Stdout)
+	n := 0
+	var all []binpuz.Entry
 	for _, k := range keys {
-		boards := m[k]
-		sort.Sort(Boards(boards))
-		fmt.Println("Difficulty", k, "boards:")
-		for _, board := range boards {
-			fmt.Printf("%v\n(%d numbers)\n\n", board, board.Count())
+		entries := m[k]
+		sort.Sort(Entries(entries))
+		for _, entry := range entries {
+			entry.Solution = entry.Puzzle.ListSolns()[0]
+			if err := w.Write(entry); err != nil {
+				panic(err)
+			}
+			n++
+			all = append(all, entry)
+			if *images != "" {
+				if err := render.WriteFile(imageName(*images, n), entry.Puzzle, nil); err != nil {
+					fmt.Fprintln(os.Stderr, err)
+				}
+			}
 		}
-		fmt.Println("-------")
 	}
+	if err := w.Flush(); err != nil {
+		panic(err)
+	}
+
+	if *pdf != "" {
+		if err := writeBooklet(*pdf, all); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+}
+
+// imageName returns the name of the nth image file. A pattern without a verb
+// for the number gets it before the extension, so every file is different.
+func imageName(pattern string, n int) string {
+	if strings.Contains(pattern, "%") {
+		return fmt.Sprintf(pattern, n)
+	}
+	ext := filepath.Ext(pattern)
+	return fmt.Sprintf("%s-%03d%s", strings.TrimSuffix(pattern, ext), n, ext)
+}
+
+// writeBooklet writes the entries as a printable booklet to the named file.
+func writeBooklet(name string, entries []binpuz.Entry) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	// Puzzles given on the command line need not be of the size set by -size.
+	title := "Binary puzzles"
+	if len(entries) > 0 {
+		title += fmt.Sprintf(", %dx%d", entries[0].Puzzle.Height, entries[0].Puzzle.Width)
+	}
+	if err := render.Booklet(f, entries, &render.BookletOptions{Title: title, PerPage: *perPage}); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
 }
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"./binpuz"
+	"./binpuz/render"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+var format = flag.String("format", "", "Read the puzzle in this format ("+strings.Join(binpuz.Formats(), ", ")+"), instead of text or a code")
+
+const help = "Arrows/hjkl: move  0 1 .: set  space: cycle  u/r: undo/redo  ?: hint  !: apply hint  c: check  q: quit"
+
+// A game is a puzzle being played.
+type game struct {
+	puzzle, board binpuz.Board
+
+	// The cursor position
+	i, j int
+
+	// Changes which can be undone and redone, most recent last
+	undo, redo []binpuz.Change
+
+	// The last hint given, cells to highlight, and a message for the player
+	hint      binpuz.Step
+	highlight []binpuz.Change
+	message   string
+}
+
+func main() {
+	flag.Parse()
+
+	// The puzzle is either given as an argument (usually a code), or on stdin.
+	// Keys are read from the terminal, so that stdin can be a file.
+	var puzzs string
+	if flag.NArg() > 0 {
+		puzzs = strings.Join(flag.Args(), "\n")
+	} else {
+		puzz, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			panic(err)
+		}
+		puzzs = strings.TrimSpace(string(puzz))
+	}
+	read := binpuz.FromString
+	if *format != "" {
+		f, err := binpuz.LookupFormat(*format)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		read = f.Read
+	}
+	p, err := read(puzzs)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		fmt.Println("A terminal is needed to play:", err)
+		os.Exit(1)
+	}
+	defer tty.Close()
+	restore, err := rawMode(tty)
+	if err != nil {
+		fmt.Println("Cannot set up the terminal:", err)
+		os.Exit(1)
+	}
+	defer restore()
+
+	g := &game{puzzle: p, board: p.Clone(), message: help}
+	g.play(tty, tty)
+	fmt.Fprint(tty, "\x1b[H\x1b[2J")
+}
+
+// rawMode puts the terminal into raw mode, so that keys can be read one at a
+// time without being echoed, and returns a function restoring its old mode.
+func rawMode(tty *os.File) (func(), error) {
+	stty := func(args ...string) ([]byte, error) {
+		cmd := exec.Command("stty", args...)
+		cmd.Stdin = tty
+		return cmd.Output()
+	}
+	state, err := stty("-g")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := stty("raw", "-echo"); err != nil {
+		return nil, err
+	}
+	return func() { stty(strings.TrimSpace(string(state))) }, nil
+}
+
+// play runs the game, reading keys from r and drawing on w, until the player
+// quits.
+func (g *game) play(r io.Reader, w io.Writer) {
+	buf := make([]byte, 8)
+	for {
+		g.draw(w)
+		n, err := r.Read(buf)
+		if err != nil {
+			return
+		}
+		// Escape sequences such as arrow keys arrive whole, but anything else
+		// may be several keys typed quickly.
+		keys := []string{string(buf[:n])}
+		if buf[0] != '\x1b' {
+			keys = strings.Split(keys[0], "")
+		}
+		for _, key := range keys {
+			if !g.handle(key) {
+				return
+			}
+		}
+	}
+}
+
+// handle acts on a single key, returning false if the player quits.
+func (g *game) handle(key string) bool {
+	switch key {
+	case "q", "\x03":
+		return false
+	case "\x1b[A", "k":
+		g.move(-1, 0)
+	case "\x1b[B", "j":
+		g.move(1, 0)
+	case "\x1b[C", "l":
+		g.move(0, 1)
+	case "\x1b[D", "h":
+		g.move(0, -1)
+	case "0":
+		g.set(binpuz.Zero)
+	case "1":
+		g.set(binpuz.One)
+	case ".", "\x7f":
+		g.set(binpuz.Empty)
+	case " ", "\r":
+		switch g.board.Get(g.i, g.j) {
+		case binpuz.Empty:
+			g.set(binpuz.Zero)
+		case binpuz.Zero:
+			g.set(binpuz.One)
+		default:
+			g.set(binpuz.Empty)
+		}
+	case "u":
+		g.undoChange()
+	case "r":
+		g.redoChange()
+	case "?":
+		g.showHint()
+	case "!":
+		g.applyHint()
+	case "c":
+		g.check()
+	default:
+		g.message = help
+	}
+	return true
+}
+
+// draw shows the board and the message, with the terminal's cursor on the
+// current cell.
+func (g *game) draw(w io.Writer) {
+	board := render.Terminal{Fancy: true}.Board(g.board, &render.Options{Puzzle: g.puzzle, Highlight: g.highlight})
+	// In raw mode, newlines do not return the cursor to the start of the line.
+	text := fmt.Sprintf("%s\n\n%s\n", g.message, board)
+	fmt.Fprint(w, "\x1b[H\x1b[2J"+strings.Replace(text, "\n", "\r\n", -1))
+	// The message takes up one line and a blank one, then each row of cells
+	// follows a grid line.
+	fmt.Fprintf(w, "\x1b[%d;%dH", 4+2*g.i, 3+4*g.j)
+}
+
+func (g *game) move(di, dj int) {
+	g.i = (g.i + di + g.board.Height) % g.board.Height
+	g.j = (g.j + dj + g.board.Width) % g.board.Width
+}
+
+// set puts a number in the current cell, unless it is a clue.
+func (g *game) set(c byte) {
+	if g.puzzle.Get(g.i, g.j) != binpuz.Empty {
+		g.message = "That cell is a clue, and cannot be changed"
+		return
+	}
+	if g.board.Get(g.i, g.j) == c {
+		return
+	}
+	g.undo = append(g.undo, g.board.Set(g.i, g.j, c))
+	g.redo = nil
+	g.validate()
+}
+
+func (g *game) undoChange() {
+	if len(g.undo) == 0 {
+		g.message = "Nothing to undo"
+		return
+	}
+	change := g.undo[len(g.undo)-1]
+	g.undo = g.undo[:len(g.undo)-1]
+	g.board.Undo(change)
+	g.redo = append(g.redo, change)
+	g.i, g.j = change.I, change.J
+	g.validate()
+}
+
+func (g *game) redoChange() {
+	if len(g.redo) == 0 {
+		g.message = "Nothing to redo"
+		return
+	}
+	change := g.redo[len(g.redo)-1]
+	g.redo = g.redo[:len(g.redo)-1]
+	g.board.Apply([]binpuz.Change{change})
+	g.undo = append(g.undo, change)
+	g.i, g.j = change.I, change.J
+	g.validate()
+}
+
+// validate checks the board against the rules after every change, and
+// highlights anything wrong.
+func (g *game) validate() {
+	g.hint = binpuz.Step{}
+	g.highlight = nil
+	switch {
+	case !g.board.Validate():
+		conflicts := g.board.Conflicts()
+		for _, cv := range conflicts {
+			g.highlight = append(g.highlight, cv.Cells...)
+		}
+		g.message = conflicts[0].Error()
+	case g.board.Solved():
+		g.message = "Solved! Press q to quit"
+	default:
+		g.message = fmt.Sprintf("%d of %d cells filled", g.board.Count(), g.board.Height*g.board.Width)
+	}
+}
+
+// showHint highlights the easiest next step.
+func (g *game) showHint() {
+	step, err := g.board.Hint()
+	var cv *binpuz.ConstraintViolation
+	switch {
+	case errors.As(err, &cv):
+		g.message = "Fix this first: " + cv.Error()
+	case err != nil:
+		g.message = err.Error()
+	default:
+		g.hint = step
+		g.highlight = step.Changes
+		g.message = fmt.Sprintf("Hint: %s (difficulty %d). Press ! to apply it", step.Reason, step.Diff)
+	}
+}
+
+// applyHint fills in the cells of the last hint shown.
+func (g *game) applyHint() {
+	if len(g.hint.Changes) == 0 {
+		g.message = "Press ? for a hint first"
+		return
+	}
+	for _, change := range g.hint.Changes {
+		g.undo = append(g.undo, g.board.Set(change.I, change.J, change.B))
+	}
+	g.redo = nil
+	g.validate()
+}
+
+// check compares the board against the solutions of the puzzle.
+func (g *game) check() {
+	mistakes, err := g.board.Mistakes(g.puzzle)
+	g.hint = binpuz.Step{}
+	g.highlight = mistakes
+	switch {
+	case len(mistakes) > 0:
+		g.message = fmt.Sprintf("%d cells are wrong", len(mistakes))
+	case err == binpuz.UnsolvableErr:
+		g.message = "No mistakes in single cells, but the board can no longer be solved"
+	case err != nil:
+		g.message = err.Error()
+	default:
+		g.message = "No mistakes so far"
+	}
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"./binpuz"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+var addr = flag.String("addr", "localhost:8080", "Address to listen on")
+var timeout = flag.Duration("timeout", 10*time.Second, "Time limit for each request")
+var workers = flag.Int("workers", 1, "Number of goroutines counting solutions")
+var maxBody = flag.Int64("max-body", 64<<10, "Size limit in bytes for request bodies")
+
+// maxLimit caps the number of solutions /count will look for, and
+// maxGenerate the height and width of puzzles from /generate, which take a
+// long time to generate when large.
+const (
+	maxLimit    = 1000
+	maxGenerate = 16
+)
+
+// A request is the JSON body of every API call. Boards are given either as
+// "puzzle", in text or as a code, or as a JSON "board".
+type request struct {
+	Puzzle string        `json:"puzzle"`
+	Board  *binpuz.Board `json:"board"`
+
+	// For /count: how many solutions to look for
+	Limit int `json:"limit"`
+
+	// For /generate: the size (or height and width), rules and random seed
+	// of the puzzle
+	Size   int            `json:"size"`
+	Height int            `json:"height"`
+	Width  int            `json:"width"`
+	Rules  binpuz.RuleSet `json:"rules"`
+	Seed   *int64         `json:"seed"`
+}
+
+// An apiError is an error to report with a particular HTTP status.
+type apiError struct {
+	status int
+	err    error
+}
+
+func (e apiError) Error() string { return e.err.Error() }
+
+// unprocessable marks err as the fault of a well-formed request, such as an
+// invalid board.
+func unprocessable(err error) error {
+	return apiError{http.StatusUnprocessableEntity, err}
+}
+
+// board returns the board given in the request, which must obey its rules.
+func (req *request) board() (binpuz.Board, error) {
+	var b binpuz.Board
+	switch {
+	case req.Board != nil:
+		b = *req.Board
+	case req.Puzzle != "":
+		var err error
+		if b, err = binpuz.FromString(req.Puzzle); err != nil {
+			return b, unprocessable(err)
+		}
+	default:
+		return b, apiError{http.StatusBadRequest, errors.New("Request must give a puzzle or board")}
+	}
+	if err := b.Check(); err != nil {
+		return b, unprocessable(err)
+	}
+	return b, nil
+}
+
+// api turns fn into a handler for POST requests with JSON bodies, with limits
+// on the size of the body and the time taken. fn is given a context which is
+// done after the time limit, or when the client goes away, and all its work
+// stops then.
+func api(fn func(ctx context.Context, req *request) (interface{}, error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeError(w, apiError{http.StatusMethodNotAllowed, errors.New("Only POST is allowed")})
+			return
+		}
+		var req request
+		dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, *maxBody))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&req); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				writeError(w, apiError{http.StatusRequestEntityTooLarge, err})
+			} else {
+				writeError(w, apiError{http.StatusBadRequest, err})
+			}
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), *timeout)
+		defer cancel()
+		resp, err := fn(ctx, &req)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		json.NewEncoder(w).Encode(resp)
+	})
+}
+
+// writeError sends err as a JSON object with an "error" field.
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	var ae apiError
+	if errors.As(err, &ae) {
+		status = ae.status
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		status = http.StatusServiceUnavailable
+		err = errors.New("Request timed out")
+	}
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// solveError marks err as unprocessable if it comes from an inconsistent
+// board, rather than from the context being done.
+func solveError(err error) error {
+	if errors.Is(err, binpuz.SolveErr) {
+		return unprocessable(err)
+	}
+	return err
+}
+
+func solve(ctx context.Context, req *request) (interface{}, error) {
+	b, err := req.board()
+	if err != nil {
+		return nil, err
+	}
+	soln, steps, err := b.SolveContext(ctx)
+	if err != nil {
+		return nil, solveError(err)
+	}
+	if steps == nil {
+		steps = []binpuz.Step{}
+	}
+	return map[string]interface{}{"solution": soln, "steps": steps, "solved": soln.Solved()}, nil
+}
+
+func count(ctx context.Context, req *request) (interface{}, error) {
+	b, err := req.board()
+	if err != nil {
+		return nil, err
+	}
+	limit := req.Limit
+	if limit <= 0 || limit > maxLimit {
+		limit = maxLimit
+	}
+	// When the search times out, the count so far is still worth giving.
+	n, err := b.CountSolnsContext(ctx, limit)
+	return map[string]interface{}{"count": n, "limit": limit, "complete": err == nil}, nil
+}
+
+func grade(ctx context.Context, req *request) (interface{}, error) {
+	b, err := req.board()
+	if err != nil {
+		return nil, err
+	}
+	diff, err := b.GradeContext(ctx)
+	if err != nil {
+		return nil, solveError(err)
+	}
+	return map[string]int{"diff": diff}, nil
+}
+
+func hint(ctx context.Context, req *request) (interface{}, error) {
+	b, err := req.board()
+	if err != nil {
+		return nil, err
+	}
+	// A hint is a single step of the solver, so it needs no context.
+	step, err := b.Hint()
+	if err != nil {
+		return nil, unprocessable(err)
+	}
+	return map[string]binpuz.Step{"step": step}, nil
+}
+
+func generate(ctx context.Context, req *request) (interface{}, error) {
+	height, width := req.Height, req.Width
+	if req.Size > 0 {
+		height, width = req.Size, req.Size
+	}
+	if height == 0 && width == 0 {
+		height, width = 10, 10
+	}
+	rules := req.Rules
+	if len(rules) == 0 {
+		rules = binpuz.Standard
+	}
+	if height > maxGenerate || width > maxGenerate {
+		return nil, unprocessable(fmt.Errorf("Generated puzzles can be at most %dx%d", maxGenerate, maxGenerate))
+	}
+	if err := rules.CheckSize(height, width); err != nil {
+		return nil, unprocessable(err)
+	}
+	seed := time.Now().UnixNano()
+	if req.Seed != nil {
+		seed = *req.Seed
+	}
+	r := rand.New(rand.NewSource(seed))
+	p, err := binpuz.GenerateContext(ctx, height, width, rules, r)
+	if err != nil {
+		return nil, err
+	}
+	// Reducing keeps the solution, which is quick to find while the puzzle
+	// has many numbers.
+	solns, err := p.ListSolnsContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// A puzzle which is only partly reduced is still a good puzzle, so
+	// reducing stops early enough to leave time to grade it.
+	rctx, cancel := context.WithTimeout(ctx, *timeout*9/10)
+	p, _ = p.ReduceContext(rctx, r, nil)
+	cancel()
+	diff, err := p.GradeContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"puzzle": p, "solution": solns[0], "diff": diff, "seed": seed}, nil
+}
+
+func main() {
+	flag.Parse()
+	binpuz.DefaultSearch.Workers = *workers
+
+	mux := http.NewServeMux()
+	mux.Handle("/solve", api(solve))
+	mux.Handle("/count", api(count))
+	mux.Handle("/grade", api(grade))
+	mux.Handle("/hint", api(hint))
+	mux.Handle("/generate", api(generate))
+
+	log.Printf("Listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, mux))
+}
@@ -2,7 +2,11 @@ package binpuz
 
 // go test -c && ./binpuz.test -test.cpuprofile=cpu.out -test.bench=. && go tool pprof binpuz.test cpu.out
 
-import "testing"
+import (
+	"context"
+	"runtime"
+	"testing"
+)
 
 // Common case, don't want this getting too slow.
 func BenchmarkUniqueEmpty10x10(b *testing.B) {
@@ -32,8 +36,57 @@ func BenchmarkCountAll6x6(b *testing.B) {
 	}
 }
 
+func BenchmarkCountAll6x6Parallel(b *testing.B) {
+	p := New(6)
+	s := Search{Workers: runtime.GOMAXPROCS(0)}
+	for i := 0; i < b.N; i++ {
+		s.CountSolns(context.Background(), p, -1)
+	}
+}
+
+func BenchmarkCountAll6x6SAT(b *testing.B) {
+	p := New(6)
+	s := Search{Backend: SAT}
+	for i := 0; i < b.N; i++ {
+		s.CountSolns(context.Background(), p, -1)
+	}
+}
+
+func BenchmarkCountAll6x6Patterns(b *testing.B) {
+	p := New(6)
+	s := Search{Backend: Patterns}
+	for i := 0; i < b.N; i++ {
+		s.CountSolns(context.Background(), p, -1)
+	}
+}
+
+// There are 4111116 solutions, far too many to count by backtracking.
+func BenchmarkCountAll8x8Patterns(b *testing.B) {
+	p := New(8)
+	s := Search{Backend: Patterns}
+	for i := 0; i < b.N; i++ {
+		s.CountSolns(context.Background(), p, -1)
+	}
+}
+
+// The bottom six rows have a few clues, and there are 11167 solutions.
+func BenchmarkCount10x10Patterns(b *testing.B) {
+	p, _ := FromString("..........\n..........\n..........\n..........\n..1..1..10\n1..0..0...\n.0..1..1..\n....11....\n.........1\n....0..00.")
+	s := Search{Backend: Patterns}
+	for i := 0; i < b.N; i++ {
+		s.CountSolns(context.Background(), p, -1)
+	}
+}
+
 func BenchmarkCountDifficult12x12(b *testing.B) {
-	vhard := `.00....1..01
+	p, _ := FromString(difficult12x12)
+	for i := 0; i < b.N; i++ {
+		p.CountSolns(-1)
+	}
+}
+
+// difficult12x12 is a puzzle which is slow to solve by backtracking.
+const difficult12x12 = `.00....1..01
 ............
 ....0.0...0.
 ..1.00...1..
@@ -45,9 +98,28 @@ func BenchmarkCountDifficult12x12(b *testing.B) {
 .0.00.....1.
 ....0......1
 1.......1.0.`
-	p, _ := FromString(vhard)
-	for i := 0; i < b.N; i++ {
-		p.CountSolns(-1)
+
+// BenchmarkOrders compares the orders of branching on a few searches.
+func BenchmarkOrders(b *testing.B) {
+	hard, _ := FromString(difficult12x12)
+	searches := []struct {
+		name   string
+		search func()
+	}{
+		{"CountAll6x6", func() { New(6).CountSolns(-1) }},
+		{"CountDifficult12x12", func() { hard.CountSolns(-1) }},
+		{"UniqueEmpty12x12", func() { New(12).HasUniqueSoln() }},
+	}
+	defer func(s Search) { DefaultSearch = s }(DefaultSearch)
+	for _, order := range []Order{RowMajor, ConstrainedLine, FullestCross, Probe} {
+		DefaultSearch = Search{Order: order}
+		for _, s := range searches {
+			b.Run(s.name+"/"+order.String(), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					s.search()
+				}
+			})
+		}
 	}
 }
 
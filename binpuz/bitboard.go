@@ -0,0 +1,153 @@
+package binpuz
+
+import "math/bits"
+
+// MaxSize is the largest board height or width which fits into a Line.
+const MaxSize = 64
+
+// A Line is a single row or column packed into two bitmasks: bit j of Filled
+// is set if cell j is not Empty, and bit j of Ones is set if cell j is One.
+// Bits beyond the end of the line are always clear.
+type Line struct {
+	Filled, Ones uint64
+}
+
+// Get returns the byte at position j of the line.
+func (l Line) Get(j int) byte {
+	m := uint64(1) << uint(j)
+	if l.Filled&m == 0 {
+		return Empty
+	}
+	if l.Ones&m != 0 {
+		return One
+	}
+	return Zero
+}
+
+// set modifies the byte at position j of the line.
+func (l *Line) set(j int, c byte) {
+	m := uint64(1) << uint(j)
+	switch c {
+	case Zero:
+		l.Filled |= m
+		l.Ones &^= m
+	case One:
+		l.Filled |= m
+		l.Ones |= m
+	default:
+		l.Filled &^= m
+		l.Ones &^= m
+	}
+}
+
+// zeros returns the mask of cells holding a Zero.
+func (l Line) zeros() uint64 { return l.Filled &^ l.Ones }
+
+// countZeroOne returns the number of zeros and the number of ones in the line.
+func (l Line) countZeroOne() (int, int) {
+	return bits.OnesCount64(l.zeros()), bits.OnesCount64(l.Ones)
+}
+
+// mask returns the mask of cells holding a One if ones is true, or holding
+// a Zero otherwise.
+func (l Line) mask(ones bool) uint64 {
+	if ones {
+		return l.Ones
+	}
+	return l.zeros()
+}
+
+// within returns true if every number in l is also in m.
+func (l Line) within(m Line) bool {
+	return l.Filled&^m.Filled == 0 && (l.Ones^m.Ones)&l.Filled == 0
+}
+
+// fullMask returns a mask with the lowest n bits set.
+func fullMask(n int) uint64 {
+	if n >= 64 {
+		return ^uint64(0)
+	}
+	return uint64(1)<<uint(n) - 1
+}
+
+// Bits is a board packed into Lines, once by rows and once by columns. It
+// is used by Board to validate itself quickly, and can be used directly
+// where only a compact representation is needed.
+type Bits struct {
+	Height, Width int
+	Rules         RuleSet
+
+	// Rows is Height long and Cols is Width long. They must be kept in
+	// sync (the easiest way is through the Set method).
+	Rows, Cols []Line
+}
+
+// NewBits creates a new blank packed board.
+func NewBits(height, width int, rules RuleSet) Bits {
+	rules = rules.orStandard()
+	rules.checkDims(height, width)
+	lines := make([]Line, height+width)
+	return Bits{height, width, rules, lines[:height], lines[height:]}
+}
+
+// Get returns the byte at position (i, j) on the board.
+func (q Bits) Get(i, j int) byte { return q.Rows[i].Get(j) }
+
+// Set modifies the byte at position (i, j) on the board.
+func (q Bits) Set(i, j int, c byte) {
+	q.Rows[i].set(j, c)
+	q.Cols[j].set(i, c)
+}
+
+// Clone makes a copy of the packed board which shares no data with the original.
+func (q Bits) Clone() Bits {
+	lines := make([]Line, q.Height+q.Width)
+	copy(lines, q.Rows)
+	copy(lines[q.Height:], q.Cols)
+	return Bits{q.Height, q.Width, q.Rules, lines[:q.Height], lines[q.Height:]}
+}
+
+// Validate returns true if the board obeys all the puzzle constraints,
+// ignoring any Empty cells.
+func (q Bits) Validate() bool {
+	return q.validLines() && q.Rules.checkBoard(q)
+}
+
+// validLines returns true if every row and column passes the per-line checks
+// of the rules.
+func (q Bits) validLines() bool {
+	return q.Rules.checkLines(q.Rows, q.Width) && q.Rules.checkLines(q.Cols, q.Height)
+}
+
+// Full returns true if the board has no Empty cells.
+func (q Bits) Full() bool {
+	full := fullMask(q.Width)
+	for _, l := range q.Rows {
+		if l.Filled != full {
+			return false
+		}
+	}
+	return true
+}
+
+// transpose returns the packed board with rows and columns swapped, sharing
+// data with the original.
+func (q Bits) transpose() Bits {
+	return Bits{q.Width, q.Height, q.Rules, q.Cols, q.Rows}
+}
+
+// Board unpacks q into a Board.
+func (q Bits) Board() Board {
+	b := NewRules(q.Height, q.Width, q.Rules)
+	for i := 0; i < q.Height; i++ {
+		for j := 0; j < q.Width; j++ {
+			b.Set(i, j, q.Get(i, j))
+		}
+	}
+	return b
+}
+
+// Bits returns a packed copy of the board.
+func (b Board) Bits() Bits {
+	return b.bits.Clone()
+}
@@ -0,0 +1,52 @@
+package binpuz
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBitsRoundTrip(t *testing.T) {
+	p, err := FromString(`.01.
+1..0
+0...
+...1`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := p.Bits()
+	for i := 0; i < p.Height; i++ {
+		for j := 0; j < p.Width; j++ {
+			if q.Get(i, j) != p.Get(i, j) {
+				t.Errorf("Bits differ from board at (%d, %d)", i, j)
+			}
+		}
+	}
+	if s := q.Board().String(); s != p.String() {
+		t.Errorf("Round trip gave\n%s\ninstead of\n%s", s, p)
+	}
+}
+
+func TestBitsValidate(t *testing.T) {
+	tests := []struct {
+		board string
+		valid bool
+	}{
+		{"0101\n1010\n0110\n1001", true},
+		{"000.\n....\n....\n....", false},
+		{"0.00\n....\n....\n....", false},
+		{"0...\n0...\n0...\n....", false},
+		{"0101\n....\n0101\n....", false},
+		{"01..\n....\n01..\n....", true},
+	}
+	for _, test := range tests {
+		// Invalid boards are still returned, along with the violation.
+		p, err := FromString(test.board)
+		var cv *ConstraintViolation
+		if err != nil && !errors.As(err, &cv) {
+			t.Fatal(err)
+		}
+		if p.Validate() != test.valid || p.Bits().Validate() != test.valid {
+			t.Errorf("Expected validity %v for board\n%s", test.valid, p)
+		}
+	}
+}
@@ -1,5 +1,11 @@
 package binpuz
 
+import (
+	"context"
+	"errors"
+	"iter"
+)
+
 // HasSoln returns true if there exists any solution for the puzzle.
 func (b Board) HasSoln() bool {
 	b = b.Clone()
@@ -7,42 +13,94 @@ func (b Board) HasSoln() bool {
 	return b.bruteHasSoln()
 }
 func (b Board) bruteHasSoln() bool {
-	n, _ := b.bruteSolve(1, false)
+	n, _ := b.countSolns(context.Background(), 1)
 	return n == 1
 }
 
 // HasUniqueSoln returns true if there exists exactly one solution for the
 // puzzle.
 func (b Board) HasUniqueSoln() bool {
-	n, _ := b.bruteSolve(2, false)
-	return n == 1
+	unique, _ := b.HasUniqueSolnContext(context.Background())
+	return unique
+}
+
+// HasUniqueSolnContext is like HasUniqueSoln, but gives up when ctx is done,
+// returning false and ctx.Err().
+func (b Board) HasUniqueSolnContext(ctx context.Context) (bool, error) {
+	n, err := b.countSolns(ctx, 2)
+	return n == 1 && err == nil, err
 }
 
 // CountSolns returns the number of distinct solutions for the puzzle, up to
 // a maximum of limit. Setting limit to -1 counts as many as possible.
 func (b Board) CountSolns(limit int) int {
-	n, _ := b.bruteSolve(limit, false)
+	n, _ := b.countSolns(context.Background(), limit)
 	return n
 }
 
+// CountSolnsContext is like CountSolns, but gives up when ctx is done,
+// returning the number of solutions found so far and ctx.Err().
+func (b Board) CountSolnsContext(ctx context.Context, limit int) (int, error) {
+	return b.countSolns(ctx, limit)
+}
+
 // ListSolns returns the distinct solutions for the puzzle.
 func (b Board) ListSolns() []Board {
-	_, solns := b.bruteSolve(0, true)
+	solns, _ := b.ListSolnsContext(context.Background())
 	return solns
 }
 
-// atMost limits the number of solutions returned (early exits). -1 to deactivate
-// listSolns returns a list of the actual moves taken for every solution returned
-func (b Board) bruteSolve(atMost int, listSolns bool) (nsolns int, solns []Board) {
+// ListSolnsContext is like ListSolns, but gives up when ctx is done,
+// returning the solutions found so far and ctx.Err().
+func (b Board) ListSolnsContext(ctx context.Context) ([]Board, error) {
+	var solns []Board
+	err := DefaultSearch.each(ctx, b, func(soln Board) bool {
+		solns = append(solns, soln.Clone())
+		return true
+	})
+	return solns, err
+}
+
+// EachSoln calls fn with each distinct solution of the puzzle in turn, until
+// fn returns false. Unlike ListSolns, only one solution is kept in memory at a
+// time: the board given to fn is reused for the next solution, so it must be
+// cloned to be kept.
+func (b Board) EachSoln(fn func(soln Board) bool) {
+	DefaultSearch.each(context.Background(), b, fn)
+}
+
+// Solns returns an iterator over the distinct solutions of the puzzle, which
+// works like EachSoln.
+func (b Board) Solns() iter.Seq[Board] {
+	return b.EachSoln
+}
+
+// EachSolnContext is like EachSoln, but stops when ctx is done, returning
+// ctx.Err().
+func (b Board) EachSolnContext(ctx context.Context, fn func(soln Board) bool) error {
+	return DefaultSearch.each(ctx, b, fn)
+}
+
+// bruteSolve calls visit with every solution found by backtracking, branching
+// on cells in the given order, until visit returns false (early exits). The
+// search stops early when ctx is done, returning ctx.Err().
+func (b Board) bruteSolve(ctx context.Context, order Order, visit func(Board) bool) (err error) {
 	// Firstly, use the definite solution methods
 	if soln, _, err := b.Solve(); err != nil {
-		return 0, nil
+		return nil
 	} else {
 		b = soln
 	}
+	done := ctx.Done()
 	// Helper method: if it returns true, early exit pls
 	var solve func(i, j int) bool
 	solve = func(i, j int) bool {
+		select {
+		case <-done:
+			err = ctx.Err()
+			return true
+		default:
+		}
 		if !b.Validate() {
 			return false
 		}
@@ -55,40 +113,62 @@ func (b Board) bruteSolve(atMost int, listSolns bool) (nsolns int, solns []Board
 		if !cont {
 			return false
 		}
-		if j == b.Size {
-			j = 0
-			i += 1
-		}
-		for ; i < b.Size; j += 1 {
-			if j == b.Size {
-				j = 0
-				i += 1
-				if i == b.Size {
-					break
-				}
-			}
-			if b.Rows[i][j] == Empty {
-				changes = append(changes, b.Set(i, j, Zero))
-				if solve(i, j+1) {
-					return true
-				}
-				b.Set(i, j, One)
-				if solve(i, j+1) {
-					return true
-				}
-				return false
+		var ok bool
+		if i, j, ok = order.next(b, i, j); ok {
+			changes = append(changes, b.Set(i, j, Zero))
+			if solve(i, j+1) {
+				return true
 			}
+			b.Set(i, j, One)
+			return solve(i, j+1)
 		}
 		// If we're here, this is a valid solution
-		nsolns++
-		if listSolns {
-			solns = append(solns, b.Clone())
-		}
-		if atMost >= 0 && nsolns >= atMost {
-			return true
-		}
-		return false
+		return !visit(b)
 	}
 	solve(0, 0)
 	return
 }
+
+// UnsolvableErr is returned by Mistakes when the board can no longer be solved.
+var UnsolvableErr = errors.New("The board can no longer be solved")
+
+// Mistakes checks a board which has been filled in from the puzzle original. It
+// returns the cells whose number differs from every solution of original, as
+// Changes holding the mistaken number. These may be perfectly valid according
+// to Validate. The error is UnsolvableErr if the board has no solutions left,
+// which can happen even when no single cell is a mistake (or when original has
+// no solutions).
+func (b Board) Mistakes(original Board) ([]Change, error) {
+	if b.Height != original.Height || b.Width != original.Width {
+		return nil, errors.New("Boards have different dimensions")
+	}
+	if original.CountSolns(1) == 0 {
+		return nil, UnsolvableErr
+	}
+
+	// A cell is right if any solution agrees with it, so rather than listing
+	// every solution, look for one with the cell filled in.
+	var mistakes []Change
+	for i, row := range b.Rows {
+		for j, c := range row {
+			if c == Empty {
+				continue
+			}
+			wrong := false
+			if clue := original.Get(i, j); clue != Empty {
+				wrong = clue != c
+			} else {
+				q := original.Clone()
+				q.Set(i, j, c)
+				wrong = q.CountSolns(1) == 0
+			}
+			if wrong {
+				mistakes = append(mistakes, Change{I: i, J: j, B: c})
+			}
+		}
+	}
+	if b.CountSolns(1) == 0 {
+		return mistakes, UnsolvableErr
+	}
+	return mistakes, nil
+}
@@ -0,0 +1,179 @@
+package binpuz
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// A CNF is a board encoded as a boolean formula in conjunctive normal form,
+// for SAT solvers. Variable i*Width+j+1 is true when cell (i, j) is One, and
+// any later variables are only needed by the encoding. Each clause is a list
+// of variables, negated when they must be false, as in the DIMACS format.
+type CNF struct {
+	Height, Width int
+	Vars          int
+	Clauses       [][]int
+}
+
+// CNF encodes the board, whose solutions are then the assignments of its
+// cell variables which can satisfy the formula. Only the rules in this
+// package can be encoded.
+func (b Board) CNF() (CNF, error) {
+	f := CNF{Height: b.Height, Width: b.Width, Vars: b.Height * b.Width}
+	for i, row := range b.Rows {
+		for j, c := range row {
+			switch c {
+			case Zero:
+				f.add(-f.cell(i, j))
+			case One:
+				f.add(f.cell(i, j))
+			}
+		}
+	}
+	for _, rule := range b.Rules {
+		for _, lines := range [][][]int{f.rows(), f.cols()} {
+			switch rule := rule.(type) {
+			case MaxRun:
+				for _, l := range lines {
+					f.maxRun(l, int(rule))
+				}
+			case Balanced:
+				for _, l := range lines {
+					limit := rule.limit(len(l))
+					f.atMost(l, limit)
+					f.atMost(negate(l), limit)
+				}
+			case UniqueLines:
+				for a := range lines {
+					for c := range lines[:a] {
+						f.differ(lines[a], lines[c])
+					}
+				}
+			default:
+				return f, fmt.Errorf("Rule %v cannot be encoded as CNF", rule)
+			}
+		}
+	}
+	return f, nil
+}
+
+// cell returns the variable for cell (i, j).
+func (f *CNF) cell(i, j int) int { return i*f.Width + j + 1 }
+
+// rows returns the variables of each row.
+func (f *CNF) rows() [][]int {
+	rows := make([][]int, f.Height)
+	for i := range rows {
+		for j := 0; j < f.Width; j++ {
+			rows[i] = append(rows[i], f.cell(i, j))
+		}
+	}
+	return rows
+}
+
+// cols returns the variables of each column.
+func (f *CNF) cols() [][]int {
+	cols := make([][]int, f.Width)
+	for j := range cols {
+		for i := 0; i < f.Height; i++ {
+			cols[j] = append(cols[j], f.cell(i, j))
+		}
+	}
+	return cols
+}
+
+// add adds a clause.
+func (f *CNF) add(lits ...int) { f.Clauses = append(f.Clauses, lits) }
+
+// newVar returns a new variable for the encoding.
+func (f *CNF) newVar() int {
+	f.Vars++
+	return f.Vars
+}
+
+func negate(lits []int) []int {
+	neg := make([]int, len(lits))
+	for k, l := range lits {
+		neg[k] = -l
+	}
+	return neg
+}
+
+// maxRun forbids more than r equal cells in a row in the line.
+func (f *CNF) maxRun(line []int, r int) {
+	for k := 0; k+r < len(line); k++ {
+		window := line[k : k+r+1]
+		f.add(append([]int(nil), window...)...)
+		f.add(negate(window)...)
+	}
+}
+
+// atMost allows at most k of lits to be true, using a sequential counter:
+// variable s[i][j] is true if at least j+1 of the first i+1 are.
+func (f *CNF) atMost(lits []int, k int) {
+	n := len(lits)
+	if k >= n {
+		return
+	}
+	if k == 0 {
+		for _, l := range lits {
+			f.add(-l)
+		}
+		return
+	}
+	s := make([][]int, n-1)
+	for i := range s {
+		s[i] = make([]int, k)
+		for j := range s[i] {
+			s[i][j] = f.newVar()
+		}
+	}
+	f.add(-lits[0], s[0][0])
+	for j := 1; j < k; j++ {
+		f.add(-s[0][j])
+	}
+	for i := 1; i < n-1; i++ {
+		f.add(-lits[i], s[i][0])
+		f.add(-s[i-1][0], s[i][0])
+		for j := 1; j < k; j++ {
+			f.add(-lits[i], -s[i-1][j-1], s[i][j])
+			f.add(-s[i-1][j], s[i][j])
+		}
+		f.add(-lits[i], -s[i-1][k-1])
+	}
+	f.add(-lits[n-1], -s[n-2][k-1])
+}
+
+// differ forbids two lines from being equal. Variable d[j] may only be true
+// if the lines differ at j, and one of them must be.
+func (f *CNF) differ(a, b []int) {
+	some := make([]int, len(a))
+	for j := range a {
+		d := f.newVar()
+		f.add(-d, a[j], b[j])
+		f.add(-d, -a[j], -b[j])
+		some[j] = d
+	}
+	f.add(some...)
+}
+
+// WriteDIMACS writes the formula in the DIMACS CNF format read by most SAT
+// solvers.
+func (f CNF) WriteDIMACS(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "c binpuz %dx%d board: variable i*%d+j+1 is true when cell (i, j) is 1\n", f.Height, f.Width, f.Width)
+	fmt.Fprintf(bw, "p cnf %d %d\n", f.Vars, len(f.Clauses))
+	var buf []byte
+	for _, c := range f.Clauses {
+		buf = buf[:0]
+		for _, l := range c {
+			buf = strconv.AppendInt(buf, int64(l), 10)
+			buf = append(buf, ' ')
+		}
+		buf = append(buf, "0\n"...)
+		bw.Write(buf)
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,96 @@
+package binpuz
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestBackendCount(t *testing.T) {
+	hard, _ := FromString(difficult12x12)
+	invalid, _ := FromString("000.\n....\n....\n....")
+	tests := []struct {
+		board Board
+		count int
+	}{
+		{New(4), 72},
+		{New(6), 4140},
+		{NewRules(2, 4, NoUnique), 6},
+		{NewRect(2, 4), 0},
+		{NewRules(3, 3, OddSized), 84},
+		{hard, 1},
+		{invalid, 0},
+	}
+	saved := DefaultSearch
+	defer func() { DefaultSearch = saved }()
+	for _, backend := range []Backend{SAT, Patterns} {
+		s := Search{Backend: backend}
+		for _, test := range tests {
+			n, err := s.CountSolns(context.Background(), test.board, -1)
+			if n != test.count || err != nil {
+				t.Errorf("Counted %d solns (%v) instead of %d with %v for\n%s", n, err, test.count, backend, test.board)
+			}
+			// Every backend must agree with backtracking, on the solutions as
+			// well as their count.
+			if test.board.Height*test.board.Width > 16 {
+				continue
+			}
+			found := map[string]bool{}
+			s.each(context.Background(), test.board, func(soln Board) bool {
+				found[soln.String()] = true
+				return soln.Solved()
+			})
+			for _, soln := range test.board.ListSolns() {
+				if !found[soln.String()] {
+					t.Errorf("%v did not find soln\n%s", backend, soln)
+				}
+			}
+		}
+
+		DefaultSearch = s
+		if !hard.HasUniqueSoln() || New(6).HasUniqueSoln() {
+			t.Errorf("Wrong uniqueness with %v", backend)
+		}
+		if n := New(6).CountSolns(10); n != 10 {
+			t.Errorf("Counted %d solns instead of 10 with %v", n, backend)
+		}
+		if solns := hard.ListSolns(); len(solns) != 1 || !solns[0].Solved() {
+			t.Errorf("Listed %d solns with %v", len(solns), backend)
+		}
+		// The next backend is still checked against backtracking.
+		DefaultSearch = saved
+	}
+}
+
+func TestDIMACS(t *testing.T) {
+	p, _ := FromString("1...\n....\n....\n...0")
+	f, err := p.CNF()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := f.WriteDIMACS(&buf); err != nil {
+		t.Fatal(err)
+	}
+	sc := bufio.NewScanner(&buf)
+	var vars, clauses int
+	var lines []string
+	for sc.Scan() {
+		switch line := sc.Text(); {
+		case strings.HasPrefix(line, "c "):
+		case strings.HasPrefix(line, "p "):
+			fmt.Sscanf(line, "p cnf %d %d", &vars, &clauses)
+		default:
+			lines = append(lines, line)
+		}
+	}
+	if vars != f.Vars || clauses != len(f.Clauses) || len(lines) != clauses {
+		t.Errorf("Header says %d vars and %d clauses, for %d vars and %d clauses", vars, clauses, f.Vars, len(lines))
+	}
+	if lines[0] != "1 0" || lines[1] != "-16 0" {
+		t.Errorf("Clues encoded as %q and %q", lines[0], lines[1])
+	}
+}
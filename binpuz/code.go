@@ -0,0 +1,136 @@
+package binpuz
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// A code is a board written on a single line, for pasting into URLs, chats
+// and spreadsheets. It looks like "6x8:c01az1b0", and consists of:
+//
+//   - the height and width, or just one number for square boards;
+//   - optional rule set letters: 'o' for OddSized, 'n' for NoUnique;
+//   - a colon, then the cells in row-major order. '0' and '1' are written as
+//     themselves, and a run of 1 to 26 Empty cells is written as one of the
+//     letters 'a' to 'z'.
+
+// codeRules are the rule sets which can be written in a code, by their letters.
+var codeRules = map[string]RuleSet{
+	"":  Standard,
+	"o": OddSized,
+	"n": NoUnique,
+}
+
+// Code returns the board written as a code, which FromCode and FromString will
+// read back. It fails if the board follows a rule set other than Standard,
+// OddSized or NoUnique.
+func (b Board) Code() (string, error) {
+	var buf bytes.Buffer
+	buf.WriteString(strconv.Itoa(b.Height))
+	if b.Width != b.Height {
+		fmt.Fprintf(&buf, "x%d", b.Width)
+	}
+	letters, ok := "", false
+	for l, rules := range codeRules {
+		if reflect.DeepEqual(rules, b.Rules.orStandard()) {
+			letters, ok = l, true
+		}
+	}
+	if !ok {
+		return "", errors.New("The board's rule set cannot be written as a code")
+	}
+	buf.WriteString(letters)
+	buf.WriteByte(':')
+
+	run := 0
+	flush := func() {
+		for ; run > 26; run -= 26 {
+			buf.WriteByte('z')
+		}
+		if run > 0 {
+			buf.WriteByte(byte('a' + run - 1))
+		}
+		run = 0
+	}
+	for _, row := range b.Rows {
+		for _, c := range row {
+			if c == Empty {
+				run++
+				continue
+			}
+			flush()
+			buf.WriteByte(c)
+		}
+	}
+	flush()
+	return buf.String(), nil
+}
+
+// isCode returns true if s looks like a code rather than a board of lines.
+func isCode(s string) bool {
+	return strings.IndexByte(s, ':') >= 0
+}
+
+// FromCode creates a board from a code, as written by Code. Like FromString, it
+// returns boards which break the rules along with a *ConstraintViolation error.
+func FromCode(s string) (Board, error) {
+	s = strings.TrimSpace(s)
+	colon := strings.IndexByte(s, ':')
+	if colon < 0 {
+		return Board{}, errors.New("Code must contain a ':'")
+	}
+	head, body := s[:colon], s[colon+1:]
+
+	// Split the head into the size and the rule set letters.
+	split := strings.IndexAny(head, "abcdefghijklmnopqrstuvwyz")
+	if split < 0 {
+		split = len(head)
+	}
+	rules, ok := codeRules[head[split:]]
+	if !ok {
+		return Board{}, fmt.Errorf("Unknown rule set %q in code", head[split:])
+	}
+	dims := strings.SplitN(head[:split], "x", 2)
+	height, err := strconv.Atoi(dims[0])
+	if err != nil {
+		return Board{}, errors.New("Invalid size in code")
+	}
+	width := height
+	if len(dims) == 2 {
+		if width, err = strconv.Atoi(dims[1]); err != nil {
+			return Board{}, errors.New("Invalid size in code")
+		}
+	}
+	if !rules.validDim(height) || !rules.validDim(width) {
+		return Board{}, errors.New("Invalid size in code")
+	}
+
+	b := NewRules(height, width, rules)
+	pos := 0
+	for k := 0; k < len(body); k++ {
+		c := body[k]
+		switch {
+		case c == Zero || c == One:
+			if pos >= height*width {
+				return Board{}, errors.New("Code has too many cells")
+			}
+			b.Set(pos/width, pos%width, c)
+			pos++
+		case 'a' <= c && c <= 'z':
+			pos += int(c-'a') + 1
+		default:
+			return Board{}, fmt.Errorf("Invalid character %q in code", c)
+		}
+	}
+	if pos != height*width {
+		return Board{}, errors.New("Code has the wrong number of cells")
+	}
+	if err := b.Check(); err != nil {
+		return b, err
+	}
+	return b, nil
+}
@@ -0,0 +1,50 @@
+package binpuz
+
+import "testing"
+
+func TestCode(t *testing.T) {
+	tests := []struct {
+		board string
+		rules RuleSet
+		code  string
+	}{
+		{"0...\n..1.\n....\n...1", Standard, "4:0e1h1"},
+		{"......\n......", Standard, "2x6:l"},
+		{"0.1\n...\n1.0", OddSized, "3o:0a1c1a0"},
+		{"0101\n1010", NoUnique, "2x4n:01011010"},
+	}
+	for _, test := range tests {
+		p, err := FromStringRules(test.board, test.rules)
+		if err != nil {
+			t.Fatal(err)
+		}
+		code, err := p.Code()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != test.code {
+			t.Errorf("Got code %q instead of %q for\n%s", code, test.code, p)
+		}
+		q, err := FromString(code)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if q.String() != p.String() || len(q.Rules) != len(p.Rules) {
+			t.Errorf("Round trip of %q gave\n%s", code, q)
+		}
+	}
+}
+
+func TestLongCode(t *testing.T) {
+	p := New(10)
+	p.Set(9, 9, One)
+	code, _ := p.Code()
+	if code != "10:zzzu1" {
+		t.Errorf("Got code %q for a board with one number", code)
+	}
+	for _, bad := range []string{"4:", "4:zz", "3:i", "4x:p", "4q:p", "4:0p"} {
+		if _, err := FromCode(bad); err == nil {
+			t.Errorf("Expected an error reading code %q", bad)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package binpuz
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// A collection is a line-oriented file of puzzles. Blank lines and lines
+// starting with '#' are ignored, and every other line is one record of
+// tab-separated fields:
+//
+//	size	rules	clues	diff	seed	puzzle	solution
+//
+// size is written like "10x10" (height by width), rules is a comma-separated
+// list of rule names, and puzzle and solution are boards with their rows
+// separated by '/'. An unknown solution is written as "-".
+
+// collectionHeader starts every collection written by a CollectionWriter.
+const collectionHeader = "# binpuz collection v1\n# size\trules\tclues\tdiff\tseed\tpuzzle\tsolution\n"
+
+// An Entry is a single puzzle in a collection.
+type Entry struct {
+	Puzzle Board
+
+	// The solution of the puzzle, or a zero Board if it is unknown.
+	Solution Board
+
+	// The difficulty grade of the puzzle, as given by Grade.
+	Diff int
+
+	// The seed of the random source the puzzle was made with, or 0 if it is
+	// unknown. What it reproduces is up to the program which made the puzzle.
+	Seed int64
+}
+
+// A CollectionReader reads entries from a collection.
+type CollectionReader struct {
+	s    *bufio.Scanner
+	line int
+}
+
+// NewCollectionReader returns a CollectionReader reading from r.
+func NewCollectionReader(r io.Reader) *CollectionReader {
+	return &CollectionReader{s: bufio.NewScanner(r)}
+}
+
+// Read returns the next entry in the collection, or io.EOF if there are none
+// left.
+func (r *CollectionReader) Read() (Entry, error) {
+	for r.s.Scan() {
+		r.line++
+		text := strings.TrimSpace(r.s.Text())
+		if text == "" || text[0] == '#' {
+			continue
+		}
+		e, err := parseEntry(text)
+		if err != nil {
+			return Entry{}, fmt.Errorf("Line %d: %v", r.line, err)
+		}
+		return e, nil
+	}
+	if err := r.s.Err(); err != nil {
+		return Entry{}, err
+	}
+	return Entry{}, io.EOF
+}
+
+// ReadCollection reads every entry of a collection.
+func ReadCollection(r io.Reader) ([]Entry, error) {
+	cr := NewCollectionReader(r)
+	var entries []Entry
+	for {
+		e, err := cr.Read()
+		if err == io.EOF {
+			return entries, nil
+		} else if err != nil {
+			return entries, err
+		}
+		entries = append(entries, e)
+	}
+}
+
+// parseEntry reads a single record of a collection.
+func parseEntry(text string) (Entry, error) {
+	fields := strings.Split(text, "\t")
+	if len(fields) != 7 {
+		return Entry{}, fmt.Errorf("Expected 7 fields, found %d", len(fields))
+	}
+	var rules RuleSet
+	for _, name := range strings.Split(fields[1], ",") {
+		rule, err := ParseRule(name)
+		if err != nil {
+			return Entry{}, err
+		}
+		rules = append(rules, rule)
+	}
+
+	var e Entry
+	var err error
+	if e.Puzzle, err = parseCollectionBoard(fields[5], rules); err != nil {
+		return Entry{}, err
+	}
+	if fields[6] != "-" {
+		if e.Solution, err = parseCollectionBoard(fields[6], rules); err != nil {
+			return Entry{}, err
+		}
+		if !e.Solution.Solved() {
+			return Entry{}, errors.New("Solution is not solved")
+		}
+		if e.Solution.Height != e.Puzzle.Height || e.Solution.Width != e.Puzzle.Width {
+			return Entry{}, errors.New("Solution does not match the puzzle")
+		}
+		for i, row := range e.Puzzle.Rows {
+			for j, c := range row {
+				if c != Empty && c != e.Solution.Get(i, j) {
+					return Entry{}, errors.New("Solution does not match the puzzle")
+				}
+			}
+		}
+	}
+	if e.Diff, err = strconv.Atoi(fields[3]); err != nil {
+		return Entry{}, err
+	}
+	if e.Seed, err = strconv.ParseInt(fields[4], 10, 64); err != nil {
+		return Entry{}, err
+	}
+
+	// The size and clue count are redundant, but checking them catches
+	// mangled records.
+	if size := fmt.Sprintf("%dx%d", e.Puzzle.Height, e.Puzzle.Width); fields[0] != size {
+		return Entry{}, fmt.Errorf("Size %s does not match the puzzle (%s)", fields[0], size)
+	}
+	if clues, err := strconv.Atoi(fields[2]); err != nil || clues != e.Puzzle.Count() {
+		return Entry{}, fmt.Errorf("Clue count %s does not match the puzzle (%d)", fields[2], e.Puzzle.Count())
+	}
+	return e, nil
+}
+
+// parseCollectionBoard reads a board with its rows separated by '/'.
+func parseCollectionBoard(s string, rules RuleSet) (Board, error) {
+	if strings.ContainsAny(s, " \t\n") {
+		return Board{}, errors.New("Unexpected whitespace in board")
+	}
+	return FromStringRules(strings.Replace(s, "/", "\n", -1), rules)
+}
+
+// A CollectionWriter writes entries to a collection. Writes are buffered, so
+// Flush must be called when done.
+type CollectionWriter struct {
+	w      *bufio.Writer
+	header bool
+}
+
+// NewCollectionWriter returns a CollectionWriter writing to w.
+func NewCollectionWriter(w io.Writer) *CollectionWriter {
+	return &CollectionWriter{w: bufio.NewWriter(w)}
+}
+
+// Write writes a single entry, starting the collection with a header if it
+// is the first one.
+func (w *CollectionWriter) Write(e Entry) error {
+	if !w.header {
+		if _, err := w.w.WriteString(collectionHeader); err != nil {
+			return err
+		}
+		w.header = true
+	}
+	rules := e.Puzzle.Rules.orStandard()
+	names := make([]string, len(rules))
+	for i, rule := range rules {
+		names[i] = fmt.Sprint(rule)
+		if _, err := ParseRule(names[i]); err != nil {
+			return fmt.Errorf("Rule %v cannot be written to a collection", rule)
+		}
+	}
+	solution := "-"
+	if e.Solution.Height > 0 {
+		solution = collectionBoard(e.Solution)
+	}
+	_, err := fmt.Fprintf(w.w, "%dx%d\t%s\t%d\t%d\t%d\t%s\t%s\n",
+		e.Puzzle.Height, e.Puzzle.Width, strings.Join(names, ","), e.Puzzle.Count(),
+		e.Diff, e.Seed, collectionBoard(e.Puzzle), solution)
+	return err
+}
+
+// Flush writes any buffered data to the underlying writer.
+func (w *CollectionWriter) Flush() error {
+	return w.w.Flush()
+}
+
+// collectionBoard returns the board with its rows separated by '/'.
+func collectionBoard(b Board) string {
+	return strings.Replace(b.String(), "\n", "/", -1)
+}
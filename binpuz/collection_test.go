@@ -0,0 +1,55 @@
+package binpuz
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCollectionRoundTrip(t *testing.T) {
+	puzzle, _ := FromString("......\n......\n....10\n..1...\n.0.0.0\n...0.0")
+	odd, _ := FromStringRules("0..\n...\n..1", OddSized)
+	entries := []Entry{
+		{Puzzle: puzzle, Solution: puzzle.ListSolns()[0], Diff: 4, Seed: 1234},
+		{Puzzle: odd, Diff: -1, Seed: -5},
+	}
+	var buf bytes.Buffer
+	w := NewCollectionWriter(&buf)
+	for _, e := range entries {
+		if err := w.Write(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	back, err := ReadCollection(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(back) != len(entries) {
+		t.Fatalf("Read %d entries instead of %d", len(back), len(entries))
+	}
+	for i, e := range entries {
+		b := back[i]
+		if b.Puzzle.String() != e.Puzzle.String() || b.Solution.String() != e.Solution.String() ||
+			b.Diff != e.Diff || b.Seed != e.Seed || len(b.Puzzle.Rules) != len(e.Puzzle.Rules.orStandard()) {
+			t.Errorf("Entry %d read back as %+v instead of %+v", i, b, e)
+		}
+	}
+}
+
+func TestCollectionErrors(t *testing.T) {
+	bad := []string{
+		"4x4\tmax-run 2,balanced,unique-lines\t1\t0\t0\t0.../..../..../....",
+		"4x4\tmax-run 2,balanced,unique-lines\t2\t0\t0\t0.../..../..../....\t-",
+		"4x6\tmax-run 2,balanced,unique-lines\t1\t0\t0\t0.../..../..../....\t-",
+		"4x4\tmax-run 2,balanced\t1\t0\t0\t0.../..../..../....\t1010/0101/0110/1001",
+	}
+	for _, text := range bad {
+		if _, err := ReadCollection(strings.NewReader(text)); err == nil {
+			t.Errorf("Expected an error reading %q", text)
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package binpuz
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A Format reads and writes boards in some notation, such as those used by
+// other puzzle collections. Like FromString, Read returns boards which break
+// the rules along with a *ConstraintViolation error.
+type Format struct {
+	Name  string
+	Read  func(s string) (Board, error)
+	Write func(b Board) (string, error)
+}
+
+var formats = make(map[string]Format)
+
+// RegisterFormat adds a format to the registry, replacing any format with the
+// same name.
+func RegisterFormat(f Format) {
+	formats[f.Name] = f
+}
+
+// LookupFormat returns the registered format with the given name.
+func LookupFormat(name string) (Format, error) {
+	f, ok := formats[name]
+	if !ok {
+		return Format{}, fmt.Errorf("Unknown format %q (known formats: %s)", name, strings.Join(Formats(), ", "))
+	}
+	return f, nil
+}
+
+// Formats returns the names of the registered formats, in sorted order.
+func Formats() []string {
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	RegisterFormat(Format{"text", FromString, func(b Board) (string, error) { return b.String(), nil }})
+	RegisterFormat(Format{"code", FromCode, Board.Code})
+	RegisterFormat(Format{"unruly", readUnruly, writeUnruly})
+	RegisterFormat(Format{"csv", readCSV, writeCSV})
+	RegisterFormat(Format{"xo", readXO, writeXO})
+}
+
+// fromCells creates a board from rows of cells, returning it along with a
+// *ConstraintViolation if it breaks the rules.
+func fromCells(rows [][]byte, rules RuleSet) (Board, error) {
+	if len(rows) == 0 {
+		return Board{}, errors.New("Board must contain data")
+	}
+	height, width := len(rows), len(rows[0])
+	if !rules.validDim(height) || !rules.validDim(width) {
+		return Board{}, errors.New("Board height and width must be even, and at most 64")
+	}
+	b := NewRules(height, width, rules)
+	for i, row := range rows {
+		if len(row) != width {
+			return Board{}, errors.New("Inconsistent board size")
+		}
+		for j, c := range row {
+			b.Set(i, j, c)
+		}
+	}
+	if err := b.Check(); err != nil {
+		return b, err
+	}
+	return b, nil
+}
+
+// The "unruly" format is the game ID of Simon Tatham's Unruly puzzle, like
+// "6x6u:aBbCd...". The parameters are the width and height, 'u' if equal lines
+// are forbidden, and optionally 'd' with a difficulty letter. In the
+// description, a lowercase letter skips that many Empty cells (a=0, b=1, ...)
+// and then places a Zero, an uppercase letter does the same with a One, and
+// 'z' or 'Z' skips 25 cells. The final letter places a cell just past the end
+// of the board.
+
+func readUnruly(s string) (Board, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "unruly:")
+	colon := strings.IndexByte(s, ':')
+	if colon < 0 {
+		return Board{}, errors.New("Unruly game ID must contain a ':'")
+	}
+	params, desc := s[:colon], s[colon+1:]
+
+	// Parameters: <w>x<h>, then 'u' and/or 'd<difficulty>'
+	end := strings.IndexAny(params, "ud")
+	if end < 0 {
+		end = len(params)
+	}
+	dims := strings.SplitN(params[:end], "x", 2)
+	w, err := strconv.Atoi(dims[0])
+	if err != nil {
+		return Board{}, errors.New("Invalid size in Unruly game ID")
+	}
+	h := w
+	if len(dims) == 2 {
+		if h, err = strconv.Atoi(dims[1]); err != nil {
+			return Board{}, errors.New("Invalid size in Unruly game ID")
+		}
+	}
+	rules := NoUnique
+	if strings.Contains(params[end:], "u") {
+		rules = Standard
+	}
+	if !rules.validDim(h) || !rules.validDim(w) {
+		return Board{}, errors.New("Invalid size in Unruly game ID")
+	}
+
+	rows := make([][]byte, h)
+	for i := range rows {
+		rows[i] = bytes.Repeat([]byte{Empty}, w)
+	}
+	pos := 0
+	for k := 0; k < len(desc); k++ {
+		c, num := desc[k], byte(Zero)
+		switch {
+		case c == 'z' || c == 'Z':
+			pos += 25
+			continue
+		case 'a' <= c && c < 'z':
+			pos += int(c - 'a')
+		case 'A' <= c && c < 'Z':
+			pos += int(c - 'A')
+			num = One
+		default:
+			return Board{}, fmt.Errorf("Invalid character %q in Unruly game ID", c)
+		}
+		if pos < w*h {
+			rows[pos/w][pos%w] = num
+		}
+		pos++
+	}
+	if pos != w*h+1 {
+		return Board{}, errors.New("Unruly game ID has the wrong number of cells")
+	}
+	return fromCells(rows, rules)
+}
+
+func writeUnruly(b Board) (string, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%dx%d", b.Width, b.Height)
+	switch rules := b.Rules.orStandard(); {
+	case reflect.DeepEqual(rules, Standard):
+		buf.WriteString("u")
+	case reflect.DeepEqual(rules, NoUnique):
+	default:
+		return "", errors.New("Unruly only supports the Standard and NoUnique rule sets")
+	}
+	buf.WriteByte(':')
+
+	run := 0
+	place := func(base byte) {
+		for ; run >= 25; run -= 25 {
+			buf.WriteByte(base + 'z' - 'a')
+		}
+		buf.WriteByte(base + byte(run))
+		run = 0
+	}
+	for _, row := range b.Rows {
+		for _, c := range row {
+			switch c {
+			case Zero:
+				place('a')
+			case One:
+				place('A')
+			default:
+				run++
+			}
+		}
+	}
+	place('a')
+	return buf.String(), nil
+}
+
+// The "csv" format has one record per row, with the cells "0", "1" or "" (or
+// ".") for Empty.
+
+func readCSV(s string) (Board, error) {
+	r := csv.NewReader(strings.NewReader(strings.TrimSpace(s)))
+	r.TrimLeadingSpace = true
+	records, err := r.ReadAll()
+	if err != nil {
+		return Board{}, err
+	}
+	rows := make([][]byte, len(records))
+	for i, record := range records {
+		rows[i] = make([]byte, len(record))
+		for j, field := range record {
+			switch strings.TrimSpace(field) {
+			case "", ".":
+				rows[i][j] = Empty
+			case "0":
+				rows[i][j] = Zero
+			case "1":
+				rows[i][j] = One
+			default:
+				return Board{}, fmt.Errorf("Invalid cell %q in CSV", field)
+			}
+		}
+	}
+	return fromCells(rows, Standard)
+}
+
+func writeCSV(b Board) (string, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	for _, row := range b.Rows {
+		record := make([]string, len(row))
+		for j, c := range row {
+			if c != Empty {
+				record[j] = string([]byte{c})
+			}
+		}
+		w.Write(record)
+	}
+	w.Flush()
+	return strings.TrimSuffix(buf.String(), "\n"), w.Error()
+}
+
+// The "xo" format is like the text format, but with 'X' for One and 'O' for
+// Zero (in either case), and '.', '-' or '_' for Empty.
+
+func readXO(s string) (Board, error) {
+	lines := strings.Fields(s)
+	rows := make([][]byte, len(lines))
+	for i, line := range lines {
+		rows[i] = make([]byte, len(line))
+		for j := 0; j < len(line); j++ {
+			switch line[j] {
+			case 'X', 'x':
+				rows[i][j] = One
+			case 'O', 'o':
+				rows[i][j] = Zero
+			case '.', '-', '_':
+				rows[i][j] = Empty
+			default:
+				return Board{}, fmt.Errorf("Invalid character %q in X/O grid", line[j])
+			}
+		}
+	}
+	return fromCells(rows, Standard)
+}
+
+func writeXO(b Board) (string, error) {
+	return strings.NewReplacer("1", "X", "0", "O").Replace(b.String()), nil
+}
@@ -0,0 +1,83 @@
+package binpuz
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFormats(t *testing.T) {
+	board := "0..1\n....\n1...\n...0"
+	tests := []struct {
+		format, text string
+	}{
+		{"text", board},
+		{"code", "4:0b1d1f0"},
+		{"unruly", "4x4u:aCEga"},
+		{"csv", "0,,,1\n,,,\n1,,,\n,,,0"},
+		{"xo", "O..X\n....\nX...\n...O"},
+	}
+	p, err := FromString(board)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range tests {
+		f, err := LookupFormat(test.format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		text, err := f.Write(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if text != test.text {
+			t.Errorf("Wrote %q instead of %q in format %s", text, test.text, test.format)
+		}
+		q, err := f.Read(test.text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if q.String() != board {
+			t.Errorf("Read\n%s\ninstead of\n%s\nin format %s", q, board, test.format)
+		}
+	}
+	if _, err := LookupFormat("nonsense"); err == nil {
+		t.Errorf("Expected an error looking up an unknown format")
+	}
+}
+
+func TestUnruly(t *testing.T) {
+	tests := []struct {
+		id    string
+		rules RuleSet
+		board string
+	}{
+		{"8x8u:zzo", Standard, New(8).String()},
+		{"unruly:4x2dt:Ed", NoUnique, "....\n1..."},
+		{"2x4:aGa", NoUnique, "0.\n..\n..\n.1"},
+	}
+	for _, test := range tests {
+		p, err := readUnruly(test.id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.String() != test.board || len(p.Rules) != len(test.rules) {
+			t.Errorf("Read %q as\n%s", test.id, p)
+		}
+	}
+	for _, bad := range []string{"4x4u", "4x4u:zz", "4x4u:p", "3x3:k", "4x4:a0"} {
+		if _, err := readUnruly(bad); err == nil {
+			t.Errorf("Expected an error reading game ID %q", bad)
+		}
+	}
+	if _, err := writeUnruly(NewRules(3, 3, OddSized)); err == nil {
+		t.Errorf("Expected an error writing an odd sized board as a game ID")
+	}
+}
+
+func TestFormatViolation(t *testing.T) {
+	_, err := readXO("XXX.\n....\n....\n....")
+	var cv *ConstraintViolation
+	if !errors.As(err, &cv) || cv.Kind != Triple {
+		t.Errorf("Expected a triple violation, got %v", err)
+	}
+}
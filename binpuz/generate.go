@@ -0,0 +1,104 @@
+package binpuz
+
+import (
+	"context"
+	"math/rand"
+)
+
+// cell is the position of one cell on a board.
+type cell struct{ i, j int }
+
+// shuffle randomly reorders cells.
+func shuffle(cells []cell, r *rand.Rand) {
+	for k := len(cells) - 1; k >= 0; k-- {
+		n := r.Intn(k + 1)
+		cells[k], cells[n] = cells[n], cells[k]
+	}
+}
+
+// Generate returns a random puzzle with a unique solution. Numbers are placed
+// in random cells until only one solution is left, so the puzzle usually has
+// many more numbers than it needs; Reduce removes them. The same random
+// source always gives the same puzzle.
+func Generate(height, width int, rules RuleSet, r *rand.Rand) Board {
+	b, _ := GenerateContext(context.Background(), height, width, rules, r)
+	return b
+}
+
+// GenerateContext is like Generate, but gives up when ctx is done, returning
+// an unfinished puzzle and ctx.Err().
+func GenerateContext(ctx context.Context, height, width int, rules RuleSet, r *rand.Rand) (Board, error) {
+	board := NewRules(height, width, rules)
+	cells := make([]cell, 0, height*width)
+	for i := 0; i < height; i++ {
+		for j := 0; j < width; j++ {
+			cells = append(cells, cell{i, j})
+		}
+	}
+	shuffle(cells, r)
+
+	// Place a random number in each cell in turn, backtracking when there
+	// are no solutions left.
+	var err error
+	var place func(idx int) bool
+	place = func(idx int) bool {
+		i, j := cells[idx].i, cells[idx].j
+		c := byte(Zero)
+		if r.Intn(2) != 0 {
+			c = One
+		}
+		board.Set(i, j, c)
+		var solns int
+		if solns, err = board.CountSolnsContext(ctx, 2); err != nil {
+			return true
+		}
+		if solns == 1 || solns >= 2 && place(idx+1) {
+			return true
+		}
+		board.Set(i, j, flip(c))
+		return place(idx + 1)
+	}
+	place(0)
+	return board, err
+}
+
+// Reduce removes numbers from a puzzle with a unique solution, in a random
+// order, for as long as the solution stays unique. It does not mutate b. If
+// visit is not nil, it is called with b and then after every number removed,
+// and must not keep the board it is given without cloning it.
+func (b Board) Reduce(r *rand.Rand, visit func(Board)) Board {
+	b, _ = b.ReduceContext(context.Background(), r, visit)
+	return b
+}
+
+// ReduceContext is like Reduce, but gives up when ctx is done, returning the
+// puzzle reduced so far (which still has a unique solution) and ctx.Err().
+func (b Board) ReduceContext(ctx context.Context, r *rand.Rand, visit func(Board)) (Board, error) {
+	b = b.Clone()
+	var cells []cell
+	for i := 0; i < b.Height; i++ {
+		for j := 0; j < b.Width; j++ {
+			if b.Get(i, j) != Empty {
+				cells = append(cells, cell{i, j})
+			}
+		}
+	}
+	if visit != nil {
+		visit(b)
+	}
+	shuffle(cells, r)
+	for _, c := range cells {
+		change := b.Set(c.i, c.j, Empty)
+		unique, err := b.HasUniqueSolnContext(ctx)
+		if err != nil {
+			b.Undo(change)
+			return b, err
+		}
+		if !unique {
+			b.Undo(change)
+		} else if visit != nil {
+			visit(b)
+		}
+	}
+	return b, nil
+}
@@ -0,0 +1,43 @@
+package binpuz
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		height, width int
+		rules         RuleSet
+	}{
+		{6, 6, Standard},
+		{4, 8, NoUnique},
+		{5, 5, OddSized},
+	}
+	for _, test := range tests {
+		p := Generate(test.height, test.width, test.rules, rand.New(rand.NewSource(1)))
+		if !p.HasUniqueSoln() {
+			t.Errorf("Generated a puzzle without a unique solution\n%s", p)
+		}
+		q := Generate(test.height, test.width, test.rules, rand.New(rand.NewSource(1)))
+		if q.String() != p.String() {
+			t.Errorf("Generated different puzzles from the same seed\n%s\n\n%s", p, q)
+		}
+
+		visits := 0
+		reduced := p.Reduce(rand.New(rand.NewSource(2)), func(b Board) { visits++ })
+		if !reduced.HasUniqueSoln() {
+			t.Errorf("Reduced a puzzle to one without a unique solution\n%s", reduced)
+		}
+		if removed := p.Count() - reduced.Count(); visits != removed+1 {
+			t.Errorf("Visited %d boards while removing %d numbers", visits, removed)
+		}
+		for i, row := range reduced.Rows {
+			for j, c := range row {
+				if c != Empty && c != p.Get(i, j) {
+					t.Errorf("Reducing changed the number at (%d, %d)", i, j)
+				}
+			}
+		}
+	}
+}
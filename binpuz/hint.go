@@ -0,0 +1,67 @@
+package binpuz
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+var (
+	// SolvedErr is returned by Hint when the board is already solved.
+	SolvedErr = errors.New("The board is already solved")
+
+	// NoHintErr is returned by Hint when none of the solver strategies can
+	// make progress, which means guessing is needed.
+	NoHintErr = errors.New("No logical step can be found")
+)
+
+// Hint returns the easiest next step towards solving the board, without
+// mutating it. The strategies of the solver are each asked for their least
+// difficult step, and the easiest of those is taken (ordering by Diff, then
+// by the number of Changes). The simple patterns, which the solver bundles
+// together, are cut down to the first change found.
+//
+// If the board breaks the rules, the error is a *ConstraintViolation.
+func (b Board) Hint() (Step, error) {
+	if err := b.Check(); err != nil {
+		return Step{}, err
+	}
+	if b.Solved() {
+		return Step{}, SolvedErr
+	}
+	var steps []Step
+	for _, strat := range b.Rules.strats(false) {
+		step := strat(b)
+		if len(step.Changes) == 0 {
+			continue
+		}
+		if step.Diff == 0 {
+			step.Changes = step.Changes[:1]
+		}
+		steps = append(steps, step)
+	}
+	if len(steps) == 0 {
+		return Step{}, NoHintErr
+	}
+	sort.Sort(stepslice(steps))
+	return steps[0], nil
+}
+
+// Nudge is like Hint, but only says which row or column to look at. The
+// Step returned has no Changes, and its Reason names the line.
+func (b Board) Nudge() (Step, error) {
+	step, err := b.Hint()
+	if err != nil {
+		return step, err
+	}
+	axis, line := step.axis, step.line
+	if line == 0 {
+		axis, line = RowAxis, step.Changes[0].I+1
+	}
+	return Step{
+		Diff:   step.Diff,
+		Reason: fmt.Sprintf("Look at %s %d", axis, line),
+		axis:   axis,
+		line:   line,
+	}, nil
+}
@@ -0,0 +1,190 @@
+package binpuz
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// The JSON forms of boards, steps and changes write each cell as a one
+// character string, using the same characters as String.
+
+// cellString returns the JSON form of a cell.
+func cellString(c byte) string { return string([]byte{c}) }
+
+// parseCell reads the JSON form of a cell.
+func parseCell(s string) (byte, error) {
+	if len(s) != 1 || (s[0] != Empty && s[0] != Zero && s[0] != One) {
+		return 0, fmt.Errorf("Invalid cell %q", s)
+	}
+	return s[0], nil
+}
+
+type changeJSON struct {
+	I     int    `json:"i"`
+	J     int    `json:"j"`
+	Value string `json:"value"`
+	Prev  string `json:"prev,omitempty"`
+}
+
+// The previous byte of a change is only written if it is a number, so that a
+// zero Prev and Empty are written the same way, and read back as Empty.
+func (c Change) MarshalJSON() ([]byte, error) {
+	cj := changeJSON{I: c.I, J: c.J, Value: cellString(c.B)}
+	if prev := c.prev(); prev != Empty {
+		cj.Prev = cellString(prev)
+	}
+	return json.Marshal(cj)
+}
+
+func (c *Change) UnmarshalJSON(data []byte) error {
+	var cj changeJSON
+	if err := json.Unmarshal(data, &cj); err != nil {
+		return err
+	}
+	b, err := parseCell(cj.Value)
+	if err != nil {
+		return err
+	}
+	prev := byte(Empty)
+	if cj.Prev != "" {
+		if prev, err = parseCell(cj.Prev); err != nil {
+			return err
+		}
+	}
+	*c = Change{cj.I, cj.J, b, prev}
+	return nil
+}
+
+type stepJSON struct {
+	Changes []Change `json:"changes"`
+	Diff    int      `json:"diff"`
+	Reason  string   `json:"reason"`
+
+	// The line the step is about, numbered from 1 as in Reason
+	Axis string `json:"axis,omitempty"`
+	Line int    `json:"line,omitempty"`
+}
+
+func (s Step) MarshalJSON() ([]byte, error) {
+	sj := stepJSON{Changes: s.Changes, Diff: s.Diff, Reason: s.Reason}
+	if sj.Changes == nil {
+		sj.Changes = []Change{}
+	}
+	if s.line > 0 {
+		sj.Axis, sj.Line = s.axis.String(), s.line
+	}
+	return json.Marshal(sj)
+}
+
+func (s *Step) UnmarshalJSON(data []byte) error {
+	var sj stepJSON
+	if err := json.Unmarshal(data, &sj); err != nil {
+		return err
+	}
+	step := Step{Changes: sj.Changes, Diff: sj.Diff, Reason: sj.Reason}
+	if sj.Line > 0 {
+		switch sj.Axis {
+		case "row":
+			step.axis = RowAxis
+		case "column":
+			step.axis = ColAxis
+		default:
+			return fmt.Errorf("Invalid axis %q", sj.Axis)
+		}
+		step.line = sj.Line
+	}
+	*s = step
+	return nil
+}
+
+// The JSON form of a RuleSet is the list of its rule names, as accepted by
+// ParseRule. Rule sets with rules from outside this package cannot be
+// written as JSON.
+func (r RuleSet) MarshalJSON() ([]byte, error) {
+	names := make([]string, len(r))
+	for i, rule := range r {
+		name := fmt.Sprint(rule)
+		if _, err := ParseRule(name); err != nil {
+			return nil, fmt.Errorf("Rule %v cannot be written as JSON", rule)
+		}
+		names[i] = name
+	}
+	return json.Marshal(names)
+}
+
+func (r *RuleSet) UnmarshalJSON(data []byte) error {
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return err
+	}
+	rules := make(RuleSet, len(names))
+	for i, name := range names {
+		rule, err := ParseRule(name)
+		if err != nil {
+			return err
+		}
+		rules[i] = rule
+	}
+	*r = rules
+	return nil
+}
+
+type boardJSON struct {
+	Height     int      `json:"height"`
+	Width      int      `json:"width"`
+	Rules      RuleSet  `json:"rules"`
+	Rows       []string `json:"rows"`
+	Transposed bool     `json:"transposed,omitempty"`
+
+	// The Grade of the board, if Solve can finish it. It is ignored when read.
+	Diff *int `json:"diff,omitempty"`
+}
+
+func (b Board) MarshalJSON() ([]byte, error) {
+	bj := boardJSON{
+		Height:     b.Height,
+		Width:      b.Width,
+		Rules:      b.Rules.orStandard(),
+		Rows:       make([]string, len(b.Rows)),
+		Transposed: b.trans,
+	}
+	for i, row := range b.Rows {
+		bj.Rows[i] = string(row)
+	}
+	if diff, err := b.Grade(); err == nil && diff >= 0 {
+		bj.Diff = &diff
+	}
+	return json.Marshal(bj)
+}
+
+// UnmarshalJSON reads a board, which does not need to obey its rules.
+func (b *Board) UnmarshalJSON(data []byte) error {
+	var bj boardJSON
+	if err := json.Unmarshal(data, &bj); err != nil {
+		return err
+	}
+	rules := bj.Rules.orStandard()
+	if !rules.validDim(bj.Height) || !rules.validDim(bj.Width) {
+		return errors.New("Invalid board height or width")
+	}
+	if len(bj.Rows) != bj.Height {
+		return errors.New("Inconsistent board size")
+	}
+	q := NewRules(bj.Height, bj.Width, rules)
+	for i, row := range bj.Rows {
+		if len(row) != bj.Width {
+			return errors.New("Inconsistent board size")
+		}
+		for j := 0; j < len(row); j++ {
+			c, err := parseCell(row[j : j+1])
+			if err != nil {
+				return err
+			}
+			q.Set(i, j, c)
+		}
+	}
+	q.trans = bj.Transposed
+	*b = q
+	return nil
+}
@@ -0,0 +1,93 @@
+package binpuz
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestBoardJSON(t *testing.T) {
+	p, _ := FromStringRules("0.1\n..1\n1..", OddSized)
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `{"height":3,"width":3,"rules":["max-run 2","balanced-odd","unique-lines"],"rows":["0.1","..1","1.."]}`
+	if string(data) != expect {
+		t.Errorf("Marshalled board as %s instead of %s", data, expect)
+	}
+	var q Board
+	if err := json.Unmarshal(data, &q); err != nil {
+		t.Fatal(err)
+	}
+	if q.String() != p.String() || !reflect.DeepEqual(q.Rules, p.Rules) {
+		t.Errorf("Round trip gave\n%s\ninstead of\n%s", q, p)
+	}
+}
+
+func TestBoardJSONDiff(t *testing.T) {
+	p, _ := FromString("01.1\n1010\n0.10\n1001")
+	diff, err := p.Grade()
+	if err != nil || diff < 0 {
+		t.Fatalf("Graded %d, %v", diff, err)
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var bj struct {
+		Diff *int `json:"diff"`
+	}
+	if err := json.Unmarshal(data, &bj); err != nil {
+		t.Fatal(err)
+	}
+	if bj.Diff == nil || *bj.Diff != diff {
+		t.Errorf("Marshalled board as %s, expected diff %d", data, diff)
+	}
+}
+
+func TestStepJSON(t *testing.T) {
+	p, _ := FromString("....\n0...\n.1..\n0.1.")
+	_, steps, err := p.Solve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(steps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back []Step
+	if err := json.Unmarshal(data, &back); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(back, steps) {
+		t.Errorf("Round trip of steps gave %+v instead of %+v", back, steps)
+	}
+}
+
+func TestChangeJSON(t *testing.T) {
+	tests := []struct {
+		change Change
+		expect string
+	}{
+		{Change{I: 1, J: 2, B: One}, `{"i":1,"j":2,"value":"1"}`},
+		{Change{1, 2, One, Empty}, `{"i":1,"j":2,"value":"1"}`},
+		{Change{1, 2, Zero, One}, `{"i":1,"j":2,"value":"0","prev":"1"}`},
+	}
+	for _, test := range tests {
+		data, err := json.Marshal(test.change)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != test.expect {
+			t.Errorf("Marshalled %+v as %s instead of %s", test.change, data, test.expect)
+		}
+		var back Change
+		if err := json.Unmarshal(data, &back); err != nil {
+			t.Fatal(err)
+		}
+		if back.prev() != test.change.prev() || back.Prev == 0 {
+			t.Errorf("Round trip of %+v gave %+v", test.change, back)
+		}
+	}
+}
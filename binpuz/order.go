@@ -0,0 +1,121 @@
+package binpuz
+
+import "math/bits"
+
+// An Order is a policy for choosing which Empty cell a backtracking search
+// branches on next. Every order finds the same solutions, but may search far
+// fewer boards to find them.
+type Order int
+
+const (
+	// RowMajor branches on the first Empty cell, reading along each row in
+	// turn.
+	RowMajor Order = iota
+
+	// ConstrainedLine branches on the first Empty cell of the row or column
+	// with the fewest Empty cells.
+	ConstrainedLine
+
+	// FullestCross branches on the Empty cell whose row and column hold the
+	// most numbers between them.
+	FullestCross
+
+	// Probe tries both numbers in every Empty cell with MaybeSolve. It
+	// branches on the first cell where either number fails, or else on the
+	// cell where the number deducing less still deduces the most.
+	Probe
+)
+
+var orderNames = []string{"row-major", "constrained-line", "fullest-cross", "probe"}
+
+func (o Order) String() string {
+	if o < 0 || int(o) >= len(orderNames) {
+		return "unknown"
+	}
+	return orderNames[o]
+}
+
+// next returns the position of the cell to branch on, and false if b is
+// full. For RowMajor, every cell before (i, j) must already be filled.
+func (o Order) next(b Board, i, j int) (int, int, bool) {
+	switch o {
+	case ConstrainedLine:
+		return constrainedLine(b)
+	case FullestCross:
+		return fullestCross(b)
+	case Probe:
+		return probe(b)
+	}
+	for ; i < b.Height; i, j = i+1, 0 {
+		for ; j < b.Width; j++ {
+			if b.Rows[i][j] == Empty {
+				return i, j, true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+func constrainedLine(b Board) (int, int, bool) {
+	best, bi, bj := 0, 0, 0
+	for i, l := range b.bits.Rows {
+		if n := b.Width - bits.OnesCount64(l.Filled); n > 0 && (best == 0 || n < best) {
+			best, bi, bj = n, i, bits.TrailingZeros64(^l.Filled)
+		}
+	}
+	for j, l := range b.bits.Cols {
+		if n := b.Height - bits.OnesCount64(l.Filled); n > 0 && (best == 0 || n < best) {
+			best, bi, bj = n, bits.TrailingZeros64(^l.Filled), j
+		}
+	}
+	return bi, bj, best > 0
+}
+
+func fullestCross(b Board) (int, int, bool) {
+	best, bi, bj := -1, 0, 0
+	for i, row := range b.bits.Rows {
+		for j, col := range b.bits.Cols {
+			if row.Filled&(1<<uint(j)) != 0 {
+				continue
+			}
+			if n := bits.OnesCount64(row.Filled) + bits.OnesCount64(col.Filled); n > best {
+				best, bi, bj = n, i, j
+			}
+		}
+	}
+	return bi, bj, best >= 0
+}
+
+func probe(b Board) (int, int, bool) {
+	best, bi, bj := -1, 0, 0
+	for i, row := range b.Rows {
+		for j, c := range row {
+			if c != Empty {
+				continue
+			}
+			score := -1
+			for _, c := range []byte{Zero, One} {
+				// MaybeSolve replaces q with a new board, so only the cell
+				// set here needs undoing.
+				q := b
+				change := q.Set(i, j, c)
+				ok := q.Validate()
+				var changes []Change
+				if ok {
+					changes, ok = q.MaybeSolve()
+				}
+				b.Undo(change)
+				if !ok {
+					return i, j, true
+				}
+				if score < 0 || len(changes) < score {
+					score = len(changes)
+				}
+			}
+			if score > best {
+				best, bi, bj = score, i, j
+			}
+		}
+	}
+	return bi, bj, best >= 0
+}
@@ -0,0 +1,186 @@
+package binpuz
+
+import (
+	"context"
+	"math/bits"
+	"sync"
+	"sync/atomic"
+)
+
+// A Search configures how solutions are searched for.
+type Search struct {
+	// The number of goroutines counting solutions at once. With 0 or 1,
+	// searches run on the calling goroutine.
+	Workers int
+
+	// The search tree is split into work units at this many branching
+	// cells. With 0, it is split into at least 8 units per worker.
+	SplitDepth int
+
+	// The order in which cells are branched on
+	Order Order
+
+	// How solutions are found. Only Backtrack searches in parallel.
+	Backend Backend
+}
+
+// A Backend is a way of finding the solutions of a board.
+type Backend int
+
+const (
+	// Backtrack branches on cells in turn, using the logical solver at every
+	// step.
+	Backtrack Backend = iota
+
+	// SAT encodes the board as CNF and runs a small DPLL solver on it. It
+	// falls back to Backtrack for rules which cannot be encoded.
+	SAT
+
+	// Patterns fills in whole rows at a time from a list of every valid
+	// line, keeping track of the lines each column can still become. With
+	// the rules in this package other than MaxDiagonalRun, it counts
+	// without visiting every solution, so it is fastest for counting the
+	// solutions of boards with few clues. It falls back to Backtrack for
+	// long lines.
+	Patterns
+)
+
+var backendNames = []string{"backtrack", "sat", "patterns"}
+
+func (k Backend) String() string {
+	if k < 0 || int(k) >= len(backendNames) {
+		return "unknown"
+	}
+	return backendNames[k]
+}
+
+// DefaultSearch is used by CountSolns, HasUniqueSoln and their Context
+// variants. Setting its Workers makes them count in parallel. Its Order and
+// Backend are also used by every other search, such as ListSolns.
+var DefaultSearch Search
+
+// each calls visit with every solution of b found the way s says, until
+// visit returns false.
+func (s Search) each(ctx context.Context, b Board, visit func(Board) bool) error {
+	switch s.Backend {
+	case SAT:
+		if f, err := b.CNF(); err == nil {
+			return f.eachSoln(ctx, b, visit)
+		}
+	case Patterns:
+		if ok, err := b.patternSolve(ctx, visit); ok {
+			return err
+		}
+	}
+	return b.bruteSolve(ctx, s.Order, visit)
+}
+
+// count counts solutions on the calling goroutine, up to a maximum of
+// atMost, or as many as possible if atMost is -1.
+func (s Search) count(ctx context.Context, b Board, atMost int) (int, error) {
+	if s.Backend == Patterns {
+		if n, ok, err := b.patternCount(ctx, atMost); ok {
+			return n, err
+		}
+	}
+	n := 0
+	err := s.each(ctx, b, func(Board) bool {
+		n++
+		return atMost < 0 || n < atMost
+	})
+	return n, err
+}
+
+// countSolns counts solutions the way DefaultSearch says.
+func (b Board) countSolns(ctx context.Context, atMost int) (int, error) {
+	if DefaultSearch.Workers > 1 {
+		return DefaultSearch.CountSolns(ctx, b, atMost)
+	}
+	return DefaultSearch.count(ctx, b, atMost)
+}
+
+// CountSolns is like Board.CountSolnsContext, but searches the way s says.
+// When counting in parallel, the search tree is split at its first few
+// branching cells, and the parts are searched by a pool of workers.
+func (s Search) CountSolns(ctx context.Context, b Board, limit int) (int, error) {
+	if s.Backend != Backtrack {
+		return s.count(ctx, b, limit)
+	}
+	// Counting stops at the first solution for any limit below one.
+	if limit == 0 {
+		limit = 1
+	}
+	var total int64
+	ctx2, cancel := context.WithCancel(ctx)
+	defer cancel()
+	visit := func(Board) bool {
+		n := atomic.AddInt64(&total, 1)
+		if limit >= 0 && n >= int64(limit) {
+			cancel()
+			return false
+		}
+		return true
+	}
+
+	depth := s.SplitDepth
+	if depth <= 0 {
+		depth = bits.Len(uint(8*s.Workers - 1))
+	}
+	units := make(chan Board)
+	var wg sync.WaitGroup
+	for k := 0; k < s.Workers || k == 0; k++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for u := range units {
+				u.bruteSolve(ctx2, s.Order, visit)
+			}
+		}()
+	}
+	if soln, _, err := b.Solve(); err == nil {
+		s.split(ctx2, soln, depth, units, visit)
+	}
+	close(units)
+	wg.Wait()
+
+	n := int(total)
+	if limit >= 0 && n > limit {
+		n = limit
+	}
+	return n, ctx.Err()
+}
+
+// split sends the boards depth branching cells below b to units, in the same
+// order as bruteSolve would search them. Solutions found on the way are given
+// to visit. It returns false if the search should stop.
+func (s Search) split(ctx context.Context, b Board, depth int, units chan<- Board, visit func(Board) bool) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if !b.Validate() {
+		return true
+	}
+	if _, ok := b.MaybeSolve(); !ok {
+		return true
+	}
+	i, j, ok := s.Order.next(b, 0, 0)
+	if !ok {
+		return visit(b)
+	}
+	if depth == 0 {
+		select {
+		case units <- b:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	for _, c := range []byte{Zero, One} {
+		q := b.Clone()
+		q.Set(i, j, c)
+		if !s.split(ctx, q, depth-1, units, visit) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,405 @@
+package binpuz
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/bits"
+	"strconv"
+	"sync"
+)
+
+// maxPatterns is the most patterns the Patterns backend uses for lines of one
+// length. Boards with longer lines are searched by backtracking instead.
+const maxPatterns = 1 << 14
+
+// patternCache holds the line patterns found so far, by length and rules.
+var patternCache = struct {
+	sync.Mutex
+	m map[string]patternList
+}{m: map[string]patternList{}}
+
+// A patternList is every full line of one length passing the per-line checks
+// of some rules, unless there are too many for ok to be set.
+type patternList struct {
+	lines []Line
+	ok    bool
+}
+
+// linePatterns returns every full line of length n which passes the per-line
+// checks of rules, in order with Zero before One at each position. It
+// returns false if there are more than maxPatterns of them.
+func linePatterns(n int, rules RuleSet) ([]Line, bool) {
+	key := strconv.Itoa(n)
+	for _, rule := range rules {
+		key += fmt.Sprintf(" %T(%v)", rule, rule)
+	}
+	patternCache.Lock()
+	defer patternCache.Unlock()
+	if p, ok := patternCache.m[key]; ok {
+		return p.lines, p.ok
+	}
+
+	// Rules ignore Empty cells, so no line starting with a broken one can
+	// pass either.
+	var p patternList
+	var l Line
+	var gen func(j int) bool
+	gen = func(j int) bool {
+		if !rules.checkLine(l, n) {
+			return true
+		}
+		if j == n {
+			p.lines = append(p.lines, l)
+			return len(p.lines) <= maxPatterns
+		}
+		for _, c := range []byte{Zero, One} {
+			l.set(j, c)
+			if !gen(j + 1) {
+				return false
+			}
+		}
+		l.set(j, Empty)
+		return true
+	}
+	p.ok = gen(0)
+	if !p.ok {
+		p.lines = nil
+	}
+	patternCache.m[key] = p
+	return p.lines, p.ok
+}
+
+// A bitset is a set of indices into a list of patterns.
+type bitset []uint64
+
+func newBitset(n int) bitset { return make(bitset, (n+63)/64) }
+
+func (s bitset) add(k int) { s[k/64] |= 1 << uint(k%64) }
+
+// and makes s the intersection of a and b.
+func (s bitset) and(a, b bitset) {
+	for k := range s {
+		s[k] = a[k] & b[k]
+	}
+}
+
+// meets returns true if s and t have an index in common.
+func (s bitset) meets(t bitset) bool {
+	for k := range s {
+		if s[k]&t[k] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// A patternBoard is a board set up for filling in a whole row at a time.
+type patternBoard struct {
+	// The board after the logical solver, and the patterns each of its rows
+	// allows by its clues
+	start Board
+	rows  [][]Line
+
+	// has[c][i] is the set of column patterns with c (0 for Zero, 1 for One)
+	// at position i, and cols[i][j] the set of patterns column j can still
+	// become once i rows are filled in.
+	has  [2][]bitset
+	cols [][]bitset
+}
+
+// newPatternBoard sets up b for filling in by patterns. It returns false if
+// the lines are too long for patterns to be used, and a nil patternBoard if
+// b has no solutions.
+func newPatternBoard(b Board) (*patternBoard, bool) {
+	rowPats, ok := linePatterns(b.Width, b.Rules)
+	if !ok {
+		return nil, false
+	}
+	colPats, ok := linePatterns(b.Height, b.Rules)
+	if !ok {
+		return nil, false
+	}
+	start, _, err := b.Solve()
+	if err != nil {
+		return nil, true
+	}
+	p := &patternBoard{start: start}
+
+	for c := range p.has {
+		p.has[c] = make([]bitset, b.Height)
+		for i := range p.has[c] {
+			p.has[c][i] = newBitset(len(colPats))
+		}
+	}
+	for k, pat := range colPats {
+		for i := 0; i < b.Height; i++ {
+			p.has[pat.Ones>>uint(i)&1][i].add(k)
+		}
+	}
+	p.cols = make([][]bitset, b.Height+1)
+	for i := range p.cols {
+		p.cols[i] = make([]bitset, b.Width)
+		for j := range p.cols[i] {
+			p.cols[i][j] = newBitset(len(colPats))
+		}
+	}
+	for j, col := range start.bits.Cols {
+		for k, pat := range colPats {
+			if pat.Ones&col.Filled == col.Ones {
+				p.cols[0][j].add(k)
+			}
+		}
+	}
+
+	p.rows = make([][]Line, b.Height)
+	for i, clues := range start.bits.Rows {
+		for _, pat := range rowPats {
+			if pat.Ones&clues.Filled == clues.Ones {
+				p.rows[i] = append(p.rows[i], pat)
+			}
+		}
+	}
+	return p, true
+}
+
+// allowed returns the cells of row i where the columns allow each number once
+// depth rows are filled in.
+func (p *patternBoard) allowed(i, depth int) (zeros, ones uint64) {
+	for j, c := range p.cols[depth] {
+		if c.meets(p.has[0][i]) {
+			zeros |= 1 << uint(j)
+		}
+		if c.meets(p.has[1][i]) {
+			ones |= 1 << uint(j)
+		}
+	}
+	return zeros, ones
+}
+
+// fill sets cols[depth+1] for row i becoming the pattern pat.
+func (p *patternBoard) fill(i, depth int, pat Line) {
+	for j, c := range p.cols[depth] {
+		p.cols[depth+1][j].and(c, p.has[pat.Ones>>uint(j)&1][i])
+	}
+}
+
+// patternSolve calls visit with every solution of b, until visit returns
+// false. Rows are filled in a whole line pattern at a time, and each column
+// keeps the set of patterns it can still become, so that rows which no
+// column pattern allows are never tried. It returns false if the lines are
+// too long for patterns to be used. Like bruteSolve, it reuses the board it
+// gives visit, and stops early when ctx is done.
+func (b Board) patternSolve(ctx context.Context, visit func(Board) bool) (bool, error) {
+	p, ok := newPatternBoard(b)
+	if !ok || p == nil {
+		return ok, nil
+	}
+	rowPats, _ := linePatterns(b.Width, b.Rules)
+	soln := p.start.Clone()
+
+	var err error
+	full := fullMask(b.Width)
+	done := ctx.Done()
+	// fits returns the cells of row i where the columns allow each number
+	// once depth rows are filled in, and the number of patterns the row can
+	// then be, counting no further than max+1.
+	fits := func(i, depth, max int) (zeros, ones uint64, n int) {
+		zeros, ones = p.allowed(i, depth)
+		for _, pat := range p.rows[i] {
+			if pat.Ones&^ones == 0 && full&^pat.Ones&^zeros == 0 {
+				if n++; n > max {
+					break
+				}
+			}
+		}
+		return zeros, ones, n
+	}
+	// Helper method: if it returns true, stop searching. Like exact cover
+	// by dancing links, the row with the fewest patterns left is filled in
+	// next, so dead ends are found as early as possible. Every row not in
+	// filled is left as it was in start.
+	var place func(filled uint64) bool
+	place = func(filled uint64) bool {
+		depth := bits.OnesCount64(filled)
+		if depth == b.Height {
+			return !visit(soln)
+		}
+		select {
+		case <-done:
+			err = ctx.Err()
+			return true
+		default:
+		}
+		i, zeros, ones, best := -1, uint64(0), uint64(0), len(rowPats)
+		for k := 0; k < b.Height; k++ {
+			if filled&(1<<uint(k)) != 0 {
+				continue
+			}
+			if z, o, n := fits(k, depth, best); i < 0 || n < best {
+				i, zeros, ones, best = k, z, o, n
+			}
+			if best == 0 {
+				return false
+			}
+		}
+		for _, pat := range p.rows[i] {
+			if pat.Ones&^ones != 0 || full&^pat.Ones&^zeros != 0 {
+				continue
+			}
+			for j := 0; j < b.Width; j++ {
+				if c := pat.Get(j); soln.Rows[i][j] != c {
+					soln.Set(i, j, c)
+				}
+			}
+			// Columns cannot break their per-line rules now, but rows can be
+			// repeated, and other rules broken.
+			if !soln.Validate() {
+				continue
+			}
+			p.fill(i, depth, pat)
+			if place(filled | 1<<uint(i)) {
+				return true
+			}
+		}
+		for j, c := range p.start.Rows[i] {
+			soln.Set(i, j, c)
+		}
+		return false
+	}
+	place(0)
+	return true, err
+}
+
+// patternCount counts the solutions of b like Search.count, without visiting
+// them. Rows are filled in from the top, and boards which agree on what the
+// rules need to know about the rows filled in so far have the same number of
+// ways to fill in the rest, so each is only counted once. For each column,
+// that is the number of ones and the run of equal cells at the bottom, and
+// with UniqueLines, also the set of rows used (which says which columns are
+// still equal). It returns false for boards with other rules, or with lines
+// too long for patterns.
+func (b Board) patternCount(ctx context.Context, atMost int) (int, bool, error) {
+	tracksRun, unique := false, false
+	for _, rule := range b.Rules {
+		switch rule.(type) {
+		case MaxRun:
+			tracksRun = true
+		case Balanced:
+		case UniqueLines:
+			unique = true
+		default:
+			return 0, false, nil
+		}
+	}
+	p, ok := newPatternBoard(b)
+	if !ok || p == nil {
+		return 0, ok, nil
+	}
+	limit := atMost
+	if limit < 0 {
+		limit = math.MaxInt64
+	} else if limit == 0 {
+		limit = 1
+	}
+
+	// state[i] holds the number of ones in each column once i rows are
+	// filled in, followed by its bottom cell and the length of its bottom
+	// run, and then the set of rows used as a bitset of indices into
+	// rowPats. It is the key to memo[i].
+	rowPats, _ := linePatterns(b.Width, b.Rules)
+	index := map[uint64]int{}
+	used := 0
+	if unique {
+		for k, pat := range rowPats {
+			index[pat.Ones] = k
+		}
+		used = (len(rowPats) + 7) / 8
+	}
+	state := make([][]byte, b.Height+1)
+	memo := make([]map[string]int, b.Height+1)
+	for i := range state {
+		state[i] = make([]byte, 3*b.Width+used)
+		memo[i] = map[string]int{}
+	}
+
+	var err error
+	full := fullMask(b.Width)
+	done := ctx.Done()
+	// count returns the number of ways to fill in the rows from i down,
+	// counting no further than limit, once cols[i] and state[i] are set.
+	var count func(i int) int
+	count = func(i int) int {
+		if i == b.Height {
+			// Every column is now a single pattern.
+			if unique {
+				for j, c := range p.cols[i] {
+					for _, d := range p.cols[i][:j] {
+						if c.meets(d) {
+							return 0
+						}
+					}
+				}
+			}
+			return 1
+		}
+		select {
+		case <-done:
+			err = ctx.Err()
+			return limit
+		default:
+		}
+		zeros, ones := p.allowed(i, i)
+		prev, next := state[i], state[i+1]
+		// With a single row left, boards hardly ever agree, so they are not
+		// worth remembering.
+		remember := i+2 < b.Height
+		n := 0
+		for _, pat := range p.rows[i] {
+			if pat.Ones&^ones != 0 || full&^pat.Ones&^zeros != 0 {
+				continue
+			}
+			if unique {
+				k := index[pat.Ones]
+				if prev[3*b.Width+k/8]&(1<<uint(k%8)) != 0 {
+					continue
+				}
+				copy(next[3*b.Width:], prev[3*b.Width:])
+				next[3*b.Width+k/8] |= 1 << uint(k%8)
+			}
+			for j := 0; j < b.Width; j++ {
+				c := byte(pat.Ones >> uint(j) & 1)
+				next[3*j] = prev[3*j] + c
+				if tracksRun {
+					next[3*j+1], next[3*j+2] = c, 1
+					if prev[3*j+2] > 0 && prev[3*j+1] == c {
+						next[3*j+2] = prev[3*j+2] + 1
+					}
+				}
+			}
+			m, ok := 0, false
+			if remember {
+				m, ok = memo[i+1][string(next)]
+			}
+			if !ok {
+				p.fill(i, i, pat)
+				m = count(i + 1)
+				if remember {
+					memo[i+1][string(next)] = m
+				}
+			}
+			if m < limit-n {
+				n += m
+			} else {
+				n = limit
+				break
+			}
+		}
+		return n
+	}
+	n := count(0)
+	if err != nil {
+		return 0, true, err
+	}
+	return n, true, nil
+}
@@ -0,0 +1,58 @@
+package binpuz
+
+import (
+	"context"
+	"testing"
+)
+
+func TestLinePatterns(t *testing.T) {
+	tests := []struct {
+		n     int
+		rules RuleSet
+		count int
+		ok    bool
+	}{
+		{6, Standard, 14, true},
+		{8, Standard, 34, true},
+		{14, NoUnique, 518, true},
+		{5, OddSized, 14, true},
+		{64, Standard, 0, false},
+	}
+	for _, test := range tests {
+		lines, ok := linePatterns(test.n, test.rules)
+		if len(lines) != test.count || ok != test.ok {
+			t.Errorf("Found %d patterns (%v) instead of %d for length %d %v", len(lines), ok, test.count, test.n, test.rules)
+		}
+	}
+}
+
+func TestPatternsCount(t *testing.T) {
+	clues, _ := FromString("00101011\n0.......\n1.......\n0.......\n1.......\n01001011\n1.......\n1.......")
+	bottom, _ := FromString("..........\n..........\n..........\n..........\n..1..1..10\n1..0..0...\n.0..1..1..\n....11....\n.........1\n....0..00.")
+	tests := []struct {
+		board Board
+		count int
+	}{
+		{NewRules(4, 4, RuleSet{MaxRun(2), Balanced{}, UniqueLines{}, MaxDiagonalRun(2)}), 2},
+		{clues, 435},
+		{bottom, 11167},
+		{NewRules(6, 6, NoUnique), 11222},
+		{NewRules(4, 6, RuleSet{MaxRun(3), Balanced{}}), 1860},
+		{NewRules(5, 5, OddSized), 8460},
+	}
+	s := Search{Backend: Patterns}
+	for _, test := range tests {
+		n, err := s.CountSolns(context.Background(), test.board, -1)
+		if n != test.count || err != nil {
+			t.Errorf("Counted %d solns (%v) instead of %d with patterns for\n%s", n, err, test.count, test.board)
+		}
+	}
+
+	// Lines too long for patterns are searched by backtracking.
+	if _, ok := linePatterns(22, NoUnique); ok {
+		t.Errorf("Lines of length 22 have too many patterns to use")
+	}
+	if n, _ := s.CountSolns(context.Background(), NewRules(2, 22, NoUnique), 1); n != 1 {
+		t.Errorf("Counted %d solns instead of 1 for a 2 x 22 board", n)
+	}
+}
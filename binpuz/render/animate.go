@@ -0,0 +1,128 @@
+package render
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"io"
+	"strings"
+	"time"
+
+	".."
+)
+
+// AnimationOptions control how GIF animates a solution.
+type AnimationOptions struct {
+	// How each frame's board is drawn. Puzzle and Highlight are set for
+	// each frame.
+	Options
+
+	// How long each step is shown, in hundredths of a second. Defaults to
+	// 150. The final board is shown for three times as long.
+	Delay int
+}
+
+// captions returns the caption of each frame of an animated solution: one for
+// the puzzle, then one for each step.
+func captions(steps []binpuz.Step) []string {
+	captions := []string{"Puzzle"}
+	for k, step := range steps {
+		captions = append(captions, fmt.Sprintf("Step %d/%d: %s (difficulty %d)", k+1, len(steps), step.Reason, step.Diff))
+	}
+	return captions
+}
+
+// wrap splits s into lines of at most n characters, breaking at spaces where
+// possible.
+func wrap(s string, n int) []string {
+	var lines []string
+	for len(s) > n {
+		k := strings.LastIndexByte(s[:n+1], ' ')
+		if k <= 0 {
+			k = n
+		}
+		lines = append(lines, s[:k])
+		s = strings.TrimLeft(s[k:], " ")
+	}
+	return append(lines, s)
+}
+
+// GIF writes an animated GIF of the puzzle being solved by steps, as returned
+// by Solve. Each frame highlights the Changes of one step, with its reason and
+// difficulty as a caption.
+func GIF(w io.Writer, puzzle binpuz.Board, steps []binpuz.Step, opts *AnimationOptions) error {
+	var o AnimationOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.Delay <= 0 {
+		o.Delay = 150
+	}
+	o.Options = o.Options.orDefault()
+	o.Puzzle = puzzle
+
+	width, height := o.size(puzzle)
+	scale := 2
+	if width < 40*(glyphWidth+1)*scale {
+		scale = 1
+	}
+	lineHeight := (glyphHeight + 3) * scale
+	perLine := (width - 2*scale) / ((glyphWidth + 1) * scale)
+	texts := captions(steps)
+	lines := make([][]string, len(texts))
+	maxLines := 0
+	for k, text := range texts {
+		lines[k] = wrap(text, perLine)
+		if len(lines[k]) > maxLines {
+			maxLines = len(lines[k])
+		}
+	}
+
+	bounds := image.Rect(0, 0, width, height+maxLines*lineHeight+scale)
+	palette := color.Palette{o.Background, o.Grid, o.Clue, o.Filled, o.HighlightColor}
+	anim := &gif.GIF{}
+	board := puzzle.Clone()
+	for k := range texts {
+		o.Highlight = nil
+		if k > 0 {
+			o.Highlight = steps[k-1].Changes
+			board.Apply(steps[k-1].Changes)
+		}
+		img := image.NewRGBA(bounds)
+		draw.Draw(img, bounds, image.NewUniform(o.Background), image.Point{}, draw.Src)
+		DrawBoard(img, image.Point{}, board, &o.Options)
+		for n, line := range lines[k] {
+			drawText(img, image.Pt(scale, height+scale+n*lineHeight+scale), line, scale, o.Clue)
+		}
+
+		frame := image.NewPaletted(bounds, palette)
+		draw.Draw(frame, bounds, img, image.Point{}, draw.Src)
+		anim.Image = append(anim.Image, frame)
+		anim.Delay = append(anim.Delay, o.Delay)
+	}
+	anim.Delay[len(anim.Delay)-1] *= 3
+	return gif.EncodeAll(w, anim)
+}
+
+// Replay shows the puzzle being solved by steps on an ANSI terminal, redrawing
+// the board for each step after the given delay. The Changes of each step are
+// highlighted.
+func Replay(w io.Writer, puzzle binpuz.Board, steps []binpuz.Step, delay time.Duration) error {
+	term := Terminal{Fancy: true}
+	board := puzzle.Clone()
+	for k, caption := range captions(steps) {
+		o := &Options{Puzzle: puzzle}
+		if k > 0 {
+			time.Sleep(delay)
+			o.Highlight = steps[k-1].Changes
+			board.Apply(o.Highlight)
+		}
+		// Move to the top left corner and clear the screen.
+		if _, err := fmt.Fprintf(w, "\x1b[H\x1b[2J%s\n\n%s\n", caption, term.Board(board, o)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package render
+
+import (
+	"bytes"
+	"image/gif"
+	"strings"
+	"testing"
+
+	".."
+)
+
+func TestAnimate(t *testing.T) {
+	p, err := binpuz.FromString("......\n...00.\n.0...1\n.0....\n....0.\n0.....")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, steps, err := p.Solve()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := GIF(&buf, p, steps, nil); err != nil {
+		t.Fatal(err)
+	}
+	anim, err := gif.DecodeAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(anim.Image) != len(steps)+1 {
+		t.Errorf("Animated %d frames instead of %d", len(anim.Image), len(steps)+1)
+	}
+
+	buf.Reset()
+	if err := Replay(&buf, p, steps, 0); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(buf.String(), "\x1b[2J"); n != len(steps)+1 {
+		t.Errorf("Replayed %d frames instead of %d", n, len(steps)+1)
+	}
+}
+
+func TestWrap(t *testing.T) {
+	lines := wrap("Only possible arrangement in row 3", 12)
+	if strings.Join(lines, "|") != "Only|possible|arrangement|in row 3" {
+		t.Errorf("Wrapped into %q", lines)
+	}
+	if lines := wrap("abcdefgh", 3); len(lines) != 3 {
+		t.Errorf("Wrapped into %q", lines)
+	}
+}
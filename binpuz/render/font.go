@@ -0,0 +1,106 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+	"unicode/utf8"
+)
+
+// glyphs is a 5x7 bitmap font of digits, capital letters and some
+// punctuation. Each row of a glyph is 5 bits, with the leftmost pixel in the
+// highest bit.
+var glyphs = map[rune][7]uint8{
+	'0':  {0x0e, 0x11, 0x13, 0x15, 0x19, 0x11, 0x0e},
+	'1':  {0x04, 0x0c, 0x04, 0x04, 0x04, 0x04, 0x0e},
+	'2':  {0x0e, 0x11, 0x01, 0x02, 0x04, 0x08, 0x1f},
+	'3':  {0x1f, 0x02, 0x04, 0x02, 0x01, 0x11, 0x0e},
+	'4':  {0x02, 0x06, 0x0a, 0x12, 0x1f, 0x02, 0x02},
+	'5':  {0x1f, 0x10, 0x1e, 0x01, 0x01, 0x11, 0x0e},
+	'6':  {0x06, 0x08, 0x10, 0x1e, 0x11, 0x11, 0x0e},
+	'7':  {0x1f, 0x01, 0x02, 0x04, 0x08, 0x08, 0x08},
+	'8':  {0x0e, 0x11, 0x11, 0x0e, 0x11, 0x11, 0x0e},
+	'9':  {0x0e, 0x11, 0x11, 0x0f, 0x01, 0x02, 0x0c},
+	'A':  {0x0e, 0x11, 0x11, 0x1f, 0x11, 0x11, 0x11},
+	'B':  {0x1e, 0x11, 0x11, 0x1e, 0x11, 0x11, 0x1e},
+	'C':  {0x0e, 0x11, 0x10, 0x10, 0x10, 0x11, 0x0e},
+	'D':  {0x1c, 0x12, 0x11, 0x11, 0x11, 0x12, 0x1c},
+	'E':  {0x1f, 0x10, 0x10, 0x1e, 0x10, 0x10, 0x1f},
+	'F':  {0x1f, 0x10, 0x10, 0x1e, 0x10, 0x10, 0x10},
+	'G':  {0x0e, 0x11, 0x10, 0x17, 0x11, 0x11, 0x0f},
+	'H':  {0x11, 0x11, 0x11, 0x1f, 0x11, 0x11, 0x11},
+	'I':  {0x0e, 0x04, 0x04, 0x04, 0x04, 0x04, 0x0e},
+	'J':  {0x07, 0x02, 0x02, 0x02, 0x02, 0x12, 0x0c},
+	'K':  {0x11, 0x12, 0x14, 0x18, 0x14, 0x12, 0x11},
+	'L':  {0x10, 0x10, 0x10, 0x10, 0x10, 0x10, 0x1f},
+	'M':  {0x11, 0x1b, 0x15, 0x15, 0x11, 0x11, 0x11},
+	'N':  {0x11, 0x11, 0x19, 0x15, 0x13, 0x11, 0x11},
+	'O':  {0x0e, 0x11, 0x11, 0x11, 0x11, 0x11, 0x0e},
+	'P':  {0x1e, 0x11, 0x11, 0x1e, 0x10, 0x10, 0x10},
+	'Q':  {0x0e, 0x11, 0x11, 0x11, 0x15, 0x12, 0x0d},
+	'R':  {0x1e, 0x11, 0x11, 0x1e, 0x14, 0x12, 0x11},
+	'S':  {0x0f, 0x10, 0x10, 0x0e, 0x01, 0x01, 0x1e},
+	'T':  {0x1f, 0x04, 0x04, 0x04, 0x04, 0x04, 0x04},
+	'U':  {0x11, 0x11, 0x11, 0x11, 0x11, 0x11, 0x0e},
+	'V':  {0x11, 0x11, 0x11, 0x11, 0x11, 0x0a, 0x04},
+	'W':  {0x11, 0x11, 0x11, 0x15, 0x15, 0x15, 0x0a},
+	'X':  {0x11, 0x11, 0x0a, 0x04, 0x0a, 0x11, 0x11},
+	'Y':  {0x11, 0x11, 0x0a, 0x04, 0x04, 0x04, 0x04},
+	'Z':  {0x1f, 0x01, 0x02, 0x04, 0x08, 0x10, 0x1f},
+	'.':  {0x00, 0x00, 0x00, 0x00, 0x00, 0x0c, 0x0c},
+	',':  {0x00, 0x00, 0x00, 0x00, 0x0c, 0x04, 0x08},
+	'\'': {0x04, 0x04, 0x08, 0x00, 0x00, 0x00, 0x00},
+	':':  {0x00, 0x0c, 0x0c, 0x00, 0x0c, 0x0c, 0x00},
+	'(':  {0x02, 0x04, 0x08, 0x08, 0x08, 0x04, 0x02},
+	')':  {0x08, 0x04, 0x02, 0x02, 0x02, 0x04, 0x08},
+	'-':  {0x00, 0x00, 0x00, 0x1f, 0x00, 0x00, 0x00},
+	'/':  {0x00, 0x01, 0x02, 0x04, 0x08, 0x10, 0x00},
+	'?':  {0x0e, 0x11, 0x01, 0x02, 0x04, 0x00, 0x04},
+	'!':  {0x04, 0x04, 0x04, 0x04, 0x04, 0x00, 0x04},
+	'%':  {0x18, 0x19, 0x02, 0x04, 0x08, 0x13, 0x03},
+}
+
+const glyphWidth, glyphHeight = 5, 7
+
+// drawGlyph draws a character as large as comfortably fits, centred in r.
+// Unknown characters are not drawn.
+func drawGlyph(dst draw.Image, r image.Rectangle, c rune, col color.Color) {
+	scale := r.Dy() * 3 / 5 / glyphHeight
+	if w := r.Dx() * 3 / 5 / glyphWidth; w < scale {
+		scale = w
+	}
+	if scale < 1 {
+		scale = 1
+	}
+	x := r.Min.X + (r.Dx()-glyphWidth*scale)/2
+	y := r.Min.Y + (r.Dy()-glyphHeight*scale)/2
+	drawText(dst, image.Pt(x, y), string(c), scale, col)
+}
+
+// drawText draws a line of text in the bitmap font, with its top left corner
+// at pt, and each pixel of the font scale pixels wide. Lowercase letters are
+// drawn as capitals, and other unknown characters as spaces.
+func drawText(dst draw.Image, pt image.Point, s string, scale int, col color.Color) {
+	src := image.NewUniform(col)
+	for _, c := range strings.ToUpper(s) {
+		glyph, ok := glyphs[c]
+		if ok {
+			for row, bits := range glyph {
+				for k := 0; k < glyphWidth; k++ {
+					if bits&(1<<uint(glyphWidth-1-k)) == 0 {
+						continue
+					}
+					px := image.Rect(0, 0, scale, scale).Add(pt.Add(image.Pt(k*scale, row*scale)))
+					draw.Draw(dst, px, src, image.Point{}, draw.Src)
+				}
+			}
+		}
+		pt.X += (glyphWidth + 1) * scale
+	}
+}
+
+// textWidth returns the width in pixels of a line of text drawn by drawText.
+func textWidth(s string, scale int) int {
+	return utf8.RuneCountInString(s) * (glyphWidth + 1) * scale
+}
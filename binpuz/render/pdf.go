@@ -0,0 +1,176 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+
+	".."
+)
+
+// BookletOptions control how Booklet lays out a collection.
+type BookletOptions struct {
+	// The title printed at the top of every page.
+	Title string
+
+	// The number of puzzles on each page. Defaults to 4.
+	PerPage int
+}
+
+// Page layout, in points. Pages are A4.
+const (
+	pageWidth, pageHeight = 595, 842
+	pageMargin            = 50
+	headerHeight          = 40
+	labelHeight           = 20
+)
+
+// Booklet writes a printable PDF of the puzzles in entries, grouped by their
+// difficulty, followed by pages of their solutions. Entries without a
+// Solution are solved with ListSolns, and must have exactly one.
+func Booklet(w io.Writer, entries []binpuz.Entry, opts *BookletOptions) error {
+	var o BookletOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.PerPage <= 0 {
+		o.PerPage = 4
+	}
+	entries = append([]binpuz.Entry(nil), entries...)
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Diff < entries[j].Diff })
+	for n := range entries {
+		if entries[n].Solution.Height > 0 {
+			continue
+		}
+		solns := entries[n].Puzzle.ListSolns()
+		if len(solns) != 1 {
+			return fmt.Errorf("Puzzle %d has %d solutions instead of 1", n+1, len(solns))
+		}
+		entries[n].Solution = solns[0]
+	}
+
+	var pages []*bytes.Buffer
+	var page *bytes.Buffer
+	slot := 0
+	// place starts a new page if needed, and returns the rectangle (left,
+	// bottom, width, height) for the next puzzle.
+	place := func(heading string, newPage bool) (float64, float64, float64, float64) {
+		if page == nil || newPage || slot == o.PerPage {
+			page = new(bytes.Buffer)
+			pages = append(pages, page)
+			slot = 0
+			if o.Title != "" {
+				text(page, "F2", 16, pageMargin, pageHeight-pageMargin-16, o.Title)
+			}
+			text(page, "F1", 12, pageWidth-pageMargin-float64(len(heading))*6, pageHeight-pageMargin-16, heading)
+		}
+		cols := int(math.Ceil(math.Sqrt(float64(o.PerPage))))
+		rows := (o.PerPage + cols - 1) / cols
+		w := float64(pageWidth-2*pageMargin) / float64(cols)
+		h := float64(pageHeight-2*pageMargin-headerHeight) / float64(rows)
+		col, row := slot%cols, slot/cols
+		slot++
+		return pageMargin + float64(col)*w, pageHeight - pageMargin - headerHeight - float64(row+1)*h, w, h
+	}
+
+	for n, e := range entries {
+		heading := fmt.Sprintf("Difficulty %d", e.Diff)
+		x, y, w, h := place(heading, n > 0 && e.Diff != entries[n-1].Diff)
+		pdfBoard(page, x, y, w, h, fmt.Sprintf("Puzzle %d", n+1), e.Puzzle, e.Puzzle)
+	}
+	for n, e := range entries {
+		x, y, w, h := place("Solutions", n == 0)
+		pdfBoard(page, x, y, w, h, fmt.Sprintf("Solution %d (difficulty %d)", n+1, e.Diff), e.Solution, e.Puzzle)
+	}
+	return writePDF(w, pages)
+}
+
+// pdfBoard draws a board with a label into the given rectangle of a page's
+// content stream. Numbers given in puzzle are drawn as clues.
+func pdfBoard(page *bytes.Buffer, x, y, w, h float64, label string, b, puzzle binpuz.Board) {
+	text(page, "F1", 10, x+10, y+h-labelHeight+6, label)
+	size := math.Min(w, h-labelHeight) - 20
+	cell := size / float64(b.Width)
+	if b.Height > b.Width {
+		cell = size / float64(b.Height)
+	}
+	left, top := x+10, y+h-labelHeight-4
+
+	fmt.Fprintf(page, "0.5 G 0.5 w\n")
+	for k := 1; k < b.Height; k++ {
+		fmt.Fprintf(page, "%.2f %.2f m %.2f %.2f l S\n", left, top-float64(k)*cell, left+float64(b.Width)*cell, top-float64(k)*cell)
+	}
+	for k := 1; k < b.Width; k++ {
+		fmt.Fprintf(page, "%.2f %.2f m %.2f %.2f l S\n", left+float64(k)*cell, top, left+float64(k)*cell, top-float64(b.Height)*cell)
+	}
+	fmt.Fprintf(page, "0 G 2 w %.2f %.2f %.2f %.2f re S\n", left, top-float64(b.Height)*cell, float64(b.Width)*cell, float64(b.Height)*cell)
+
+	fontSize := cell * 0.6
+	for i := 0; i < b.Height; i++ {
+		for j := 0; j < b.Width; j++ {
+			c := b.Get(i, j)
+			if c == binpuz.Empty {
+				continue
+			}
+			font := "F2"
+			if puzzle.Get(i, j) == binpuz.Empty {
+				font = "F1"
+				page.WriteString("0.13 0.31 0.75 rg\n")
+			}
+			// Helvetica digits are 0.556 em wide, and about 0.7 em tall.
+			cx := left + (float64(j)+0.5)*cell - fontSize*0.278
+			cy := top - (float64(i)+0.5)*cell - fontSize*0.35
+			text(page, font, fontSize, cx, cy, string(c))
+			if font == "F1" {
+				page.WriteString("0 g\n")
+			}
+		}
+	}
+}
+
+// text adds a line of text to a page's content stream.
+func text(page *bytes.Buffer, font string, size, x, y float64, s string) {
+	s = strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`).Replace(s)
+	fmt.Fprintf(page, "BT /%s %.2f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, y, s)
+}
+
+// writePDF writes a PDF document with the given page content streams, which
+// may use Helvetica as font F1 and Helvetica-Bold as font F2.
+func writePDF(w io.Writer, pages []*bytes.Buffer) error {
+	var buf bytes.Buffer
+	// Objects 1 to 4 are the catalog, the page tree and the fonts, and
+	// each page then takes two objects: the page and its content stream.
+	offsets := make([]int, 5+2*len(pages))
+	object := func(n int, body string) {
+		offsets[n] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", n, body)
+	}
+
+	buf.WriteString("%PDF-1.4\n")
+	object(1, "<< /Type /Catalog /Pages 2 0 R >>")
+	kids := make([]string, len(pages))
+	for k := range pages {
+		kids[k] = fmt.Sprintf("%d 0 R", 5+2*k)
+	}
+	object(2, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	object(3, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>")
+	object(4, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold >>")
+	for k, page := range pages {
+		object(5+2*k, fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, 6+2*k))
+		object(6+2*k, fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", page.Len(), page.String()))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets))
+	for _, off := range offsets[1:] {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets), xref)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
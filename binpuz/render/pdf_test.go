@@ -0,0 +1,54 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	".."
+)
+
+func TestBooklet(t *testing.T) {
+	var entries []binpuz.Entry
+	for _, s := range []string{"......\n...00.\n.0...1\n.0....\n....0.\n0.....", "....\n0...\n.1..\n0.1."} {
+		p, err := binpuz.FromString(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, binpuz.Entry{Puzzle: p, Diff: 2})
+	}
+	entries[1].Diff = 1
+
+	var buf bytes.Buffer
+	err := Booklet(&buf, entries, &BookletOptions{Title: "Test (1)", PerPage: 2})
+	if err == nil {
+		t.Fatal("Expected an error for a puzzle with several solutions")
+	}
+	entries = entries[:1]
+	if err := Booklet(&buf, entries, &BookletOptions{Title: "Test (1)", PerPage: 2}); err != nil {
+		t.Fatal(err)
+	}
+	pdf := buf.String()
+	if !strings.HasPrefix(pdf, "%PDF-") || !strings.HasSuffix(pdf, "%%EOF\n") {
+		t.Errorf("Not a PDF file")
+	}
+	if !strings.Contains(pdf, "/Count 2 ") || !strings.Contains(pdf, `(Test \(1\))`) || !strings.Contains(pdf, `(Solution 1 \(difficulty 2\))`) {
+		t.Errorf("Missing pages or labels in\n%s", pdf)
+	}
+
+	// Every object must be where the cross-reference table says it is.
+	xref := pdf[strings.LastIndex(pdf, "\nxref\n"):]
+	offsets := regexp.MustCompile(`(\d{10}) 00000 n`).FindAllStringSubmatch(xref, -1)
+	if len(offsets) != 8 {
+		t.Errorf("Found %d objects instead of 8", len(offsets))
+	}
+	for n, off := range offsets {
+		k, _ := strconv.Atoi(off[1])
+		if !strings.HasPrefix(pdf[k:], fmt.Sprintf("%d 0 obj", n+1)) {
+			t.Errorf("Object %d is not at offset %d", n+1, k)
+		}
+	}
+}
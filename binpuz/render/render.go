@@ -0,0 +1,226 @@
+// Package render draws binpuz boards as images, in SVG or PNG.
+package render
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	".."
+)
+
+// Options control how a board is drawn. The zero value of each field means
+// its default.
+type Options struct {
+	// The size of a cell in pixels. Defaults to 32.
+	CellSize int
+
+	// The original puzzle. Numbers given in it are drawn as clues, and all
+	// other numbers as filled in. If it is a zero Board, every number is a
+	// clue.
+	Puzzle binpuz.Board
+
+	// Cells to highlight, such as the Changes of a Step.
+	Highlight []binpuz.Change
+
+	// The width in pixels of the lines between cells, and of the border
+	// around the board. They default to 1 and 3.
+	LineWidth, BorderWidth int
+
+	Background, Grid, Clue, Filled, HighlightColor color.Color
+}
+
+// DefaultOptions are the options used for nil Options, and for the zero fields
+// of other Options.
+var DefaultOptions = Options{
+	CellSize:       32,
+	LineWidth:      1,
+	BorderWidth:    3,
+	Background:     color.White,
+	Grid:           color.Gray{0x80},
+	Clue:           color.Black,
+	Filled:         color.RGBA{0x20, 0x50, 0xc0, 0xff},
+	HighlightColor: color.RGBA{0xff, 0xe0, 0x60, 0xff},
+}
+
+// orDefault returns the options with every zero field set to its default.
+func (o *Options) orDefault() Options {
+	d := DefaultOptions
+	if o == nil {
+		return d
+	}
+	opts := *o
+	if opts.CellSize <= 0 {
+		opts.CellSize = d.CellSize
+	}
+	if opts.LineWidth <= 0 {
+		opts.LineWidth = d.LineWidth
+	}
+	if opts.BorderWidth <= 0 {
+		opts.BorderWidth = d.BorderWidth
+	}
+	for _, c := range []struct {
+		opt *color.Color
+		def color.Color
+	}{
+		{&opts.Background, d.Background},
+		{&opts.Grid, d.Grid},
+		{&opts.Clue, d.Clue},
+		{&opts.Filled, d.Filled},
+		{&opts.HighlightColor, d.HighlightColor},
+	} {
+		if *c.opt == nil {
+			*c.opt = c.def
+		}
+	}
+	return opts
+}
+
+// isClue returns true if the number at (i, j) was given in the puzzle.
+func (o Options) isClue(i, j int) bool {
+	return o.Puzzle.Height == 0 || o.Puzzle.Get(i, j) != binpuz.Empty
+}
+
+// highlighted returns true if the cell at (i, j) is to be highlighted.
+func (o Options) highlighted(i, j int) bool {
+	for _, c := range o.Highlight {
+		if c.I == i && c.J == j {
+			return true
+		}
+	}
+	return false
+}
+
+// size returns the width and height of the drawn board in pixels.
+func (o Options) size(b binpuz.Board) (int, int) {
+	return b.Width*o.CellSize + 2*o.BorderWidth, b.Height*o.CellSize + 2*o.BorderWidth
+}
+
+// Image draws the board.
+func Image(b binpuz.Board, opts *Options) *image.RGBA {
+	o := opts.orDefault()
+	w, h := o.size(b)
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	DrawBoard(img, image.Pt(0, 0), b, opts)
+	return img
+}
+
+// DrawBoard draws the board onto dst, with its top left corner at pt.
+func DrawBoard(dst draw.Image, pt image.Point, b binpuz.Board, opts *Options) {
+	o := opts.orDefault()
+	fill := func(r image.Rectangle, c color.Color) {
+		draw.Draw(dst, r.Add(pt), image.NewUniform(c), image.Point{}, draw.Src)
+	}
+	w, h := o.size(b)
+	fill(image.Rect(0, 0, w, h), o.Grid)
+
+	cs, bw := o.CellSize, o.BorderWidth
+	half := o.LineWidth / 2
+	for i := 0; i < b.Height; i++ {
+		for j := 0; j < b.Width; j++ {
+			x, y := bw+j*cs, bw+i*cs
+			// Leave the grid line showing around each cell.
+			cell := image.Rect(x+half, y+half, x+cs-(o.LineWidth-half), y+cs-(o.LineWidth-half))
+			if o.highlighted(i, j) {
+				fill(cell, o.HighlightColor)
+			} else {
+				fill(cell, o.Background)
+			}
+			c := b.Get(i, j)
+			if c == binpuz.Empty {
+				continue
+			}
+			col := o.Filled
+			if o.isClue(i, j) {
+				col = o.Clue
+			}
+			drawGlyph(dst, cell.Add(pt), rune(c), col)
+		}
+	}
+	// Draw the border over the outermost cells' grid lines.
+	fill(image.Rect(0, 0, w, bw), o.Clue)
+	fill(image.Rect(0, h-bw, w, h), o.Clue)
+	fill(image.Rect(0, 0, bw, h), o.Clue)
+	fill(image.Rect(w-bw, 0, w, h), o.Clue)
+}
+
+// PNG writes the board as a PNG image.
+func PNG(w io.Writer, b binpuz.Board, opts *Options) error {
+	return png.Encode(w, Image(b, opts))
+}
+
+// SVG writes the board as an SVG image.
+func SVG(w io.Writer, b binpuz.Board, opts *Options) error {
+	o := opts.orDefault()
+	width, height := o.size(b)
+	cs, bw := o.CellSize, o.BorderWidth
+
+	var buf strings.Builder
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n", width, height, width, height)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="%s"/>`+"\n", width, height, hex(o.Background))
+	for i := 0; i < b.Height; i++ {
+		for j := 0; j < b.Width; j++ {
+			x, y := bw+j*cs, bw+i*cs
+			if o.highlighted(i, j) {
+				fmt.Fprintf(&buf, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s"/>`+"\n", x, y, cs, cs, hex(o.HighlightColor))
+			}
+			c := b.Get(i, j)
+			if c == binpuz.Empty {
+				continue
+			}
+			col, weight := o.Filled, "normal"
+			if o.isClue(i, j) {
+				col, weight = o.Clue, "bold"
+			}
+			fmt.Fprintf(&buf, `<text x="%d" y="%d" font-family="sans-serif" font-size="%d" font-weight="%s" text-anchor="middle" dominant-baseline="central" fill="%s">%c</text>`+"\n",
+				x+cs/2, y+cs/2, cs*3/5, weight, hex(col), c)
+		}
+	}
+	for k := 1; k < b.Height; k++ {
+		fmt.Fprintf(&buf, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="%s" stroke-width="%d"/>`+"\n", bw, bw+k*cs, width-bw, bw+k*cs, hex(o.Grid), o.LineWidth)
+	}
+	for k := 1; k < b.Width; k++ {
+		fmt.Fprintf(&buf, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="%s" stroke-width="%d"/>`+"\n", bw+k*cs, bw, bw+k*cs, height-bw, hex(o.Grid), o.LineWidth)
+	}
+	fmt.Fprintf(&buf, `<rect x="%g" y="%g" width="%d" height="%d" fill="none" stroke="%s" stroke-width="%d"/>`+"\n",
+		float64(bw)/2, float64(bw)/2, width-bw, height-bw, hex(o.Clue), bw)
+	buf.WriteString("</svg>\n")
+
+	_, err := io.WriteString(w, buf.String())
+	return err
+}
+
+// hex returns a color in the "#rrggbb" notation.
+func hex(c color.Color) string {
+	r, g, b, _ := c.RGBA()
+	return fmt.Sprintf("#%02x%02x%02x", r>>8, g>>8, b>>8)
+}
+
+// WriteFile draws the board into the named file, as SVG or PNG depending on
+// its extension.
+func WriteFile(name string, b binpuz.Board, opts *Options) error {
+	var write func(io.Writer, binpuz.Board, *Options) error
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".svg":
+		write = SVG
+	case ".png":
+		write = PNG
+	default:
+		return fmt.Errorf("Unknown image type for %s (expected .svg or .png)", name)
+	}
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := write(f, b, opts); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,52 @@
+package render
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+
+	".."
+)
+
+func TestRender(t *testing.T) {
+	puzzle, err := binpuz.FromString("0..1\n....\n1...\n...0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	board := puzzle.Clone()
+	change := board.Set(0, 1, binpuz.One)
+	opts := &Options{CellSize: 20, Puzzle: puzzle, Highlight: []binpuz.Change{change}}
+
+	var buf bytes.Buffer
+	if err := PNG(&buf, board, opts); err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := img.Bounds().Size(); size.X != 86 || size.Y != 86 {
+		t.Errorf("Drew a %v image instead of 86x86", size)
+	}
+	// The middle of the highlighted cell is part of the glyph, so check
+	// its corner.
+	if hex(img.At(3+20+2, 3+2)) != hex(DefaultOptions.HighlightColor) {
+		t.Errorf("Highlighted cell is %s", hex(img.At(3+20+2, 3+2)))
+	}
+	if hex(img.At(3+40+2, 3+2)) != hex(DefaultOptions.Background) {
+		t.Errorf("Empty cell is %s", hex(img.At(3+40+2, 3+2)))
+	}
+
+	buf.Reset()
+	if err := SVG(&buf, board, opts); err != nil {
+		t.Fatal(err)
+	}
+	svg := buf.String()
+	if n := strings.Count(svg, "<text"); n != 5 {
+		t.Errorf("Drew %d numbers instead of 5", n)
+	}
+	if n := strings.Count(svg, `font-weight="bold"`); n != 4 {
+		t.Errorf("Drew %d clues instead of 4", n)
+	}
+}
@@ -0,0 +1,88 @@
+package render
+
+import (
+	"os"
+	"strings"
+
+	".."
+)
+
+// A Terminal draws boards as text. If Fancy is set, boards have box-drawing
+// grid lines, and clues, filled in numbers and highlighted cells are shown in
+// different colors using ANSI escape codes. Otherwise boards are drawn just
+// like Board.String.
+type Terminal struct {
+	Fancy bool
+}
+
+// ANSI escape codes used by Terminal.
+const (
+	ansiReset     = "\x1b[0m"
+	ansiClue      = "\x1b[1m"
+	ansiFilled    = "\x1b[34m"
+	ansiHighlight = "\x1b[1;30;43m"
+	ansiGrid      = "\x1b[90m"
+)
+
+// NewTerminal returns a Terminal for writing to f, which is fancy if f is a
+// terminal. The NO_COLOR environment variable, or a TERM of "dumb", turn
+// fancy output off.
+func NewTerminal(f *os.File) Terminal {
+	return Terminal{Fancy: IsTerminal(f) && os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb"}
+}
+
+// IsTerminal returns true if f is a terminal (or another character device).
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Board returns the board drawn as text, without a final newline. Only the
+// Puzzle and Highlight fields of opts are used.
+func (t Terminal) Board(b binpuz.Board, opts *Options) string {
+	if !t.Fancy {
+		return b.String()
+	}
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+
+	var buf strings.Builder
+	rule := func(left, mid, right string) {
+		buf.WriteString(ansiGrid + left)
+		for j := 0; j < b.Width; j++ {
+			if j > 0 {
+				buf.WriteString(mid)
+			}
+			buf.WriteString("───")
+		}
+		buf.WriteString(right + ansiReset)
+	}
+	rule("┌", "┬", "┐")
+	for i := 0; i < b.Height; i++ {
+		buf.WriteByte('\n')
+		if i > 0 {
+			rule("├", "┼", "┤")
+			buf.WriteByte('\n')
+		}
+		for j := 0; j < b.Width; j++ {
+			buf.WriteString(ansiGrid + "│" + ansiReset)
+			c, color := b.Get(i, j), ansiFilled
+			switch {
+			case o.highlighted(i, j):
+				color = ansiHighlight
+			case c == binpuz.Empty:
+				buf.WriteString("   ")
+				continue
+			case o.isClue(i, j):
+				color = ansiClue
+			}
+			buf.WriteString(color + " " + string(c) + " " + ansiReset)
+		}
+		buf.WriteString(ansiGrid + "│" + ansiReset)
+	}
+	buf.WriteByte('\n')
+	rule("└", "┴", "┘")
+	return buf.String()
+}
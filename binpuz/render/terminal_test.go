@@ -0,0 +1,33 @@
+package render
+
+import (
+	"strings"
+	"testing"
+
+	".."
+)
+
+func TestTerminal(t *testing.T) {
+	puzzle, err := binpuz.FromString("0...\n....\n....\n...1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	board := puzzle.Clone()
+	change := board.Set(0, 1, binpuz.One)
+	board.Set(0, 2, binpuz.Zero)
+	opts := &Options{Puzzle: puzzle, Highlight: []binpuz.Change{change}}
+
+	if s := (Terminal{}).Board(board, opts); s != board.String() {
+		t.Errorf("Plain output differs from String:\n%s", s)
+	}
+
+	s := Terminal{Fancy: true}.Board(board, opts)
+	if n := strings.Count(s, "\n"); n != 2*board.Height {
+		t.Errorf("Drew %d lines instead of %d", n+1, 2*board.Height+1)
+	}
+	for _, cell := range []string{ansiClue + " 0 ", ansiHighlight + " 1 ", ansiFilled + " 0 ", ansiClue + " 1 "} {
+		if !strings.Contains(s, cell) {
+			t.Errorf("Missing cell %q in\n%s", cell, s)
+		}
+	}
+}
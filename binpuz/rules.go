@@ -0,0 +1,296 @@
+package binpuz
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// A Rule is one of the constraints a puzzle variant places on its boards.
+// Rules work on the packed representation of a board, and must ignore
+// Empty cells.
+type Rule interface {
+	// CheckLine returns true if a row or column of length n is consistent
+	// with the rule.
+	CheckLine(l Line, n int) bool
+
+	// CheckBoard returns true if the whole board is consistent with the
+	// rule. This is for constraints which cannot be checked one line at a
+	// time, such as comparing lines with each other.
+	CheckBoard(q Bits) bool
+}
+
+// A Propagator is a Rule which can also deduce cells by itself. Propagate
+// works like any other solver strategy: it does not mutate the board, and
+// returns its least difficult Step (or a Step with no Changes).
+type Propagator interface {
+	Rule
+	Propagate(b Board) Step
+}
+
+// A RuleSet is the collection of rules a board follows.
+type RuleSet []Rule
+
+var (
+	// Standard puzzles have an even height and width, no three equal
+	// adjacent cells in a line, as many zeros as ones in every line, and no
+	// two equal full rows or columns.
+	Standard = RuleSet{MaxRun(2), Balanced{}, UniqueLines{}}
+
+	// OddSized puzzles are like Standard ones, but may have an odd height
+	// or width. Every line of length n holds floor(n/2) or ceil(n/2) of each
+	// digit.
+	OddSized = RuleSet{MaxRun(2), Balanced{Odd: true}, UniqueLines{}}
+
+	// NoUnique puzzles are like Standard ones, but allow equal full lines.
+	NoUnique = RuleSet{MaxRun(2), Balanced{}}
+)
+
+// orStandard returns r, or Standard if r is empty.
+func (r RuleSet) orStandard() RuleSet {
+	if len(r) == 0 {
+		return Standard
+	}
+	return r
+}
+
+// checkLines returns true if every line in lines, each of length n, passes
+// the per-line check of every rule.
+func (r RuleSet) checkLines(lines []Line, n int) bool {
+	for _, l := range lines {
+		if !r.checkLine(l, n) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkLine returns true if a line of length n passes the per-line check of
+// every rule.
+func (r RuleSet) checkLine(l Line, n int) bool {
+	for _, rule := range r {
+		if !rule.CheckLine(l, n) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkBoard returns true if q passes the whole-board check of every rule.
+func (r RuleSet) checkBoard(q Bits) bool {
+	for _, rule := range r {
+		if !rule.CheckBoard(q) {
+			return false
+		}
+	}
+	return true
+}
+
+// strats returns the solver strategies for boards following r: first the
+// propagation hooks of the rules, then the general line completion
+// strategies. If assist is true, only the cheaper strategies are returned.
+func (r RuleSet) strats(assist bool) []func(Board) Step {
+	var strats []func(Board) Step
+	for _, rule := range r {
+		if p, ok := rule.(Propagator); ok {
+			strats = append(strats, p.Propagate)
+		}
+	}
+	strats = append(strats, completeRowsSmall)
+	if !assist {
+		strats = append(strats, completeRowsFull)
+	}
+	return strats
+}
+
+// limit returns the largest number of either digit allowed in a line of
+// length n.
+func (r RuleSet) limit(n int) int {
+	for _, rule := range r {
+		if bal, ok := rule.(Balanced); ok {
+			return bal.limit(n)
+		}
+	}
+	return n
+}
+
+// validDim returns true if a board may have n rows or columns.
+func (r RuleSet) validDim(n int) bool {
+	if n <= 0 || n > MaxSize {
+		return false
+	}
+	for _, rule := range r {
+		if bal, ok := rule.(Balanced); ok && !bal.Odd && n%2 != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckSize returns an error if boards following the rules cannot have the
+// given height and width. An empty rule set means Standard, as in NewRules.
+func (r RuleSet) CheckSize(height, width int) error {
+	r = r.orStandard()
+	for _, n := range []int{height, width} {
+		if n <= 0 || n > MaxSize {
+			return errors.New("Height and width should be positive numbers, at most 64!")
+		}
+		if !r.validDim(n) {
+			return errors.New("Height and width should be even numbers for this rule set!")
+		}
+	}
+	return nil
+}
+
+// checkDims panics if a board cannot have the given dimensions.
+func (r RuleSet) checkDims(height, width int) {
+	if err := r.CheckSize(height, width); err != nil {
+		panic(err)
+	}
+}
+
+// MaxRun forbids runs of more than the given number of equal adjacent cells
+// in a row or column. The standard rule is MaxRun(2).
+type MaxRun int
+
+// run returns the mask of cells starting a run of more than r set bits in m.
+func (r MaxRun) run(m uint64) uint64 {
+	if r == 2 {
+		return m & (m >> 1) & (m >> 2)
+	}
+	x := m
+	for k := 1; k <= int(r) && x != 0; k++ {
+		x &= m >> uint(k)
+	}
+	return x
+}
+
+func (r MaxRun) CheckLine(l Line, n int) bool {
+	return r.run(l.zeros()) == 0 && r.run(l.Ones) == 0
+}
+
+func (r MaxRun) CheckBoard(q Bits) bool { return true }
+
+func (r MaxRun) String() string { return fmt.Sprintf("max-run %d", int(r)) }
+
+func (r MaxRun) Propagate(b Board) Step {
+	if r == 2 {
+		return fixedRepls(b)
+	}
+	return runRepls(b, int(r))
+}
+
+// Balanced limits the number of each digit in a row or column to half its
+// length, which for even lengths means there are as many zeros as ones. If
+// Odd is set, lines of odd length n may hold up to ceil(n/2) of a digit.
+type Balanced struct {
+	Odd bool
+}
+
+// limit returns the largest number of either digit allowed in a line of
+// length n.
+func (r Balanced) limit(n int) int {
+	if r.Odd {
+		return (n + 1) / 2
+	}
+	return n / 2
+}
+
+func (r Balanced) CheckLine(l Line, n int) bool {
+	zeros, ones := l.countZeroOne()
+	limit := r.limit(n)
+	return zeros <= limit && ones <= limit
+}
+
+func (r Balanced) CheckBoard(q Bits) bool { return true }
+
+func (r Balanced) String() string {
+	if r.Odd {
+		return "balanced-odd"
+	}
+	return "balanced"
+}
+
+func (r Balanced) Propagate(b Board) Step {
+	return remainingNos(b, r)
+}
+
+// UniqueLines forbids two full rows, or two full columns, from being equal.
+type UniqueLines struct{}
+
+func (r UniqueLines) CheckLine(l Line, n int) bool { return true }
+
+func (r UniqueLines) String() string { return "unique-lines" }
+
+func (r UniqueLines) CheckBoard(q Bits) bool {
+	return uniqueLines(q.Rows, q.Width) && uniqueLines(q.Cols, q.Height)
+}
+
+// uniqueLines returns true if no two full lines of length n are equal.
+func uniqueLines(lines []Line, n int) bool {
+	full := fullMask(n)
+	for i, l := range lines {
+		// Any earlier line equal to this one must be full too.
+		if l.Filled != full {
+			continue
+		}
+		for _, prev := range lines[:i] {
+			if prev == l {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// MaxDiagonalRun forbids runs of more than the given number of equal cells
+// along any diagonal or anti-diagonal.
+type MaxDiagonalRun int
+
+func (r MaxDiagonalRun) CheckLine(l Line, n int) bool { return true }
+
+func (r MaxDiagonalRun) String() string { return fmt.Sprintf("max-diagonal-run %d", int(r)) }
+
+func (r MaxDiagonalRun) CheckBoard(q Bits) bool {
+	k := int(r)
+	for i := 0; i+k < q.Height; i++ {
+		for _, ones := range []bool{false, true} {
+			// Diagonals going down and to the right, then down and to the left.
+			down, up := q.Rows[i].mask(ones), q.Rows[i].mask(ones)
+			for t := 1; t <= k; t++ {
+				m := q.Rows[i+t].mask(ones)
+				down &= m >> uint(t)
+				up &= m << uint(t)
+			}
+			if down != 0 || up != 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// ParseRule returns the rule of this package with the given name, as given by
+// its String method.
+func ParseRule(name string) (Rule, error) {
+	fields := strings.Fields(name)
+	switch {
+	case name == "balanced":
+		return Balanced{}, nil
+	case name == "balanced-odd":
+		return Balanced{Odd: true}, nil
+	case name == "unique-lines":
+		return UniqueLines{}, nil
+	case len(fields) == 2 && (fields[0] == "max-run" || fields[0] == "max-diagonal-run"):
+		n, err := strconv.Atoi(fields[1])
+		if err != nil || n <= 0 {
+			break
+		}
+		if fields[0] == "max-run" {
+			return MaxRun(n), nil
+		}
+		return MaxDiagonalRun(n), nil
+	}
+	return nil, fmt.Errorf("Unknown rule %q", name)
+}
@@ -0,0 +1,55 @@
+package binpuz
+
+import "testing"
+
+func TestCountRuleSetSolns(t *testing.T) {
+	tests := []struct {
+		name          string
+		rules         RuleSet
+		height, width int
+		expect        int
+	}{
+		{"no unique", NoUnique, 4, 4, 90},
+		{"no unique", NoUnique, 6, 6, 11222},
+		{"max run 3", RuleSet{MaxRun(3), Balanced{}}, 4, 6, 1860},
+		{"diagonals", RuleSet{MaxRun(2), Balanced{}, UniqueLines{}, MaxDiagonalRun(2)}, 4, 4, 2},
+	}
+	for _, test := range tests {
+		solns := NewRules(test.height, test.width, test.rules).CountSolns(-1)
+		if test.expect != solns {
+			t.Errorf("Counted %d solns instead of %d for %d x %d board (%s)", solns, test.expect, test.height, test.width, test.name)
+		}
+	}
+}
+
+func TestMaxRunPropagate(t *testing.T) {
+	p, err := FromStringRules("000.1.\n......", RuleSet{MaxRun(3), Balanced{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	step := MaxRun(3).Propagate(p)
+	if len(step.Changes) != 1 || step.Changes[0] != (Change{0, 3, One, Empty}) {
+		t.Errorf("Expected a single change placing a 1, got %v", step.Changes)
+	}
+}
+
+func TestCheckSize(t *testing.T) {
+	tests := []struct {
+		rules         RuleSet
+		height, width int
+		ok            bool
+	}{
+		{Standard, 10, 10, true},
+		{nil, 4, 6, true},
+		{nil, 5, 5, false},
+		{Standard, 6, 7, false},
+		{OddSized, 5, 7, true},
+		{OddSized, 0, 4, false},
+		{OddSized, 4, MaxSize + 1, false},
+	}
+	for _, test := range tests {
+		if err := test.rules.CheckSize(test.height, test.width); (err == nil) != test.ok {
+			t.Errorf("Checking a %d x %d board gave %v", test.height, test.width, err)
+		}
+	}
+}
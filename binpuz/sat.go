@@ -0,0 +1,222 @@
+package binpuz
+
+import "context"
+
+// A satSolver is a small DPLL solver, with unit propagation by two watched
+// literals and chronological backtracking. It is much simpler than the
+// solvers reading DIMACS files, but independent of the rest of the package.
+type satSolver struct {
+	clauses [][]int
+
+	// The clauses watching each literal (see litIndex)
+	watches [][]int
+
+	// The value of each variable: 1 if true, -1 if false, 0 if unassigned
+	value []int8
+
+	// Assigned literals in order, of which those before head have been
+	// propagated
+	trail []int
+	head  int
+
+	// The decisions made, most recent last
+	levels []satLevel
+
+	// Set if the formula is unsatisfiable without making any decisions
+	unsat bool
+}
+
+// A satLevel is a decision to make lit true, taken when the trail was trail
+// long. Once flipped, the opposite decision has already been tried.
+type satLevel struct {
+	lit, trail int
+	flipped    bool
+}
+
+func litIndex(l int) int {
+	if l > 0 {
+		return 2 * l
+	}
+	return -2*l + 1
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func newSATSolver(f CNF) *satSolver {
+	s := &satSolver{watches: make([][]int, 2*f.Vars+2), value: make([]int8, f.Vars+1)}
+	for _, c := range f.Clauses {
+		switch len(c) {
+		case 0:
+			s.unsat = true
+		case 1:
+			if !s.assign(c[0]) {
+				s.unsat = true
+			}
+		default:
+			// Watched literals are kept at the front, so clauses are reordered.
+			c = append([]int(nil), c...)
+			k := len(s.clauses)
+			s.clauses = append(s.clauses, c)
+			s.watches[litIndex(c[0])] = append(s.watches[litIndex(c[0])], k)
+			s.watches[litIndex(c[1])] = append(s.watches[litIndex(c[1])], k)
+		}
+	}
+	return s
+}
+
+// val returns the value of a literal.
+func (s *satSolver) val(l int) int8 {
+	if l < 0 {
+		return -s.value[-l]
+	}
+	return s.value[l]
+}
+
+// assign makes l true, returning false if it is already false.
+func (s *satSolver) assign(l int) bool {
+	switch s.val(l) {
+	case 1:
+		return true
+	case -1:
+		return false
+	}
+	if l > 0 {
+		s.value[l] = 1
+	} else {
+		s.value[-l] = -1
+	}
+	s.trail = append(s.trail, l)
+	return true
+}
+
+// propagate assigns every literal forced by a clause with one literal left,
+// returning false if a clause cannot be satisfied.
+func (s *satSolver) propagate() bool {
+	for s.head < len(s.trail) {
+		f := -s.trail[s.head]
+		s.head++
+		ws := s.watches[litIndex(f)]
+		kept, conflict := ws[:0], false
+		for _, k := range ws {
+			c := s.clauses[k]
+			if conflict {
+				kept = append(kept, k)
+				continue
+			}
+			if c[0] == f {
+				c[0], c[1] = c[1], c[0]
+			}
+			if s.val(c[0]) == 1 {
+				kept = append(kept, k)
+				continue
+			}
+			moved := false
+			for m := 2; m < len(c); m++ {
+				if s.val(c[m]) != -1 {
+					c[1], c[m] = c[m], c[1]
+					s.watches[litIndex(c[1])] = append(s.watches[litIndex(c[1])], k)
+					moved = true
+					break
+				}
+			}
+			if !moved {
+				kept = append(kept, k)
+				conflict = !s.assign(c[0])
+			}
+		}
+		s.watches[litIndex(f)] = kept
+		if conflict {
+			return false
+		}
+	}
+	return true
+}
+
+// undo unassigns everything after the first n literals of the trail.
+func (s *satSolver) undo(n int) {
+	for _, l := range s.trail[n:] {
+		s.value[abs(l)] = 0
+	}
+	s.trail = s.trail[:n]
+	s.head = n
+}
+
+// backtrack flips the most recent decision which has not been flipped yet,
+// skipping decisions on variables after primary if it is positive. It
+// returns false when there are none left.
+func (s *satSolver) backtrack(primary int) bool {
+	for len(s.levels) > 0 {
+		l := &s.levels[len(s.levels)-1]
+		s.undo(l.trail)
+		if l.flipped || primary > 0 && abs(l.lit) > primary {
+			s.levels = s.levels[:len(s.levels)-1]
+			continue
+		}
+		l.lit, l.flipped = -l.lit, true
+		s.assign(l.lit)
+		return true
+	}
+	return false
+}
+
+// each calls visit after finding each satisfying assignment with different
+// values for the first primary variables, until visit returns false. The
+// search stops early when ctx is done, returning ctx.Err().
+func (s *satSolver) each(ctx context.Context, primary int, visit func() bool) error {
+	if s.unsat {
+		return nil
+	}
+	done := ctx.Done()
+	for {
+		if !s.propagate() {
+			if !s.backtrack(0) {
+				return nil
+			}
+			continue
+		}
+		// Deciding the variables in order makes every primary variable
+		// decided before the others, so once an assignment is found, the
+		// rest of the others can be skipped.
+		v := 1
+		for v < len(s.value) && s.value[v] != 0 {
+			v++
+		}
+		if v == len(s.value) {
+			if !visit() || !s.backtrack(primary) {
+				return nil
+			}
+			continue
+		}
+		select {
+		case <-done:
+			return ctx.Err()
+		default:
+		}
+		s.levels = append(s.levels, satLevel{-v, len(s.trail), false})
+		s.assign(-v)
+	}
+}
+
+// eachSoln calls visit with every solution of b, which f encodes, until
+// visit returns false. Like bruteSolve, it reuses the board it gives visit.
+func (f CNF) eachSoln(ctx context.Context, b Board, visit func(Board) bool) error {
+	s := newSATSolver(f)
+	soln := b.Clone()
+	return s.each(ctx, f.Height*f.Width, func() bool {
+		for i := 0; i < f.Height; i++ {
+			for j := 0; j < f.Width; j++ {
+				c := byte(Zero)
+				if s.value[f.cell(i, j)] > 0 {
+					c = One
+				}
+				soln.Set(i, j, c)
+			}
+		}
+		return visit(soln)
+	})
+}
@@ -2,6 +2,7 @@ package binpuz
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"sort"
@@ -21,21 +22,30 @@ func flip(b byte) byte {
 	return Zero
 }
 
-// rowcol returns "row" if the board is not transposed, and "column" otherwise.
-func (b Board) rowcol() string {
+// axis returns RowAxis if the board is not transposed, and ColAxis otherwise.
+func (b Board) axis() Axis {
 	if b.trans {
-		return "column"
+		return ColAxis
 	}
-	return "row"
+	return RowAxis
 }
 
+// rowcol returns "row" if the board is not transposed, and "column" otherwise.
+func (b Board) rowcol() string {
+	return b.axis().String()
+}
+
+// SolveErr is returned by Solve when the board is, or becomes, inconsistent. Solve
+// wraps it together with the *ConstraintViolation which was found.
 var SolveErr = errors.New("Solving led to an inconsistent configuration")
 
-// fixedRepls directly applies the no-three-adjacent rule, by making substitions like "00." => "001"
+// fixedRepls directly applies the no-three-adjacent rule (MaxRun(2)), by making substitions like "00." => "001"
 // and "0.0" => "010". It will keep applying these until it can make no progress, then bundle them
 // all up and hand back a Step of difficulty 0.
 func fixedRepls(b Board) Step {
 	var changes []Change
+	var axis Axis
+	var line int
 	for progress := true; progress; {
 		progress = false
 		for _, q := range b.Views() {
@@ -60,6 +70,9 @@ func fixedRepls(b Board) Step {
 					}
 
 					if idx >= 0 {
+						if len(changes) == 0 {
+							axis, line = q.axis(), i+1
+						}
 						changes = append(changes, q.Set(i, idx, repl))
 						progress = true
 					}
@@ -73,13 +86,57 @@ func fixedRepls(b Board) Step {
 		Changes: changes,
 		Diff:    0,
 		Reason:  "Apply simple patterns",
+		axis:    axis,
+		line:    line,
+	}
+}
+
+// runRepls generalises fixedRepls to the MaxRun(k) rule: whenever k+1 adjacent cells
+// contain k equal numbers and one blank, the blank must be the other number.
+func runRepls(b Board, k int) Step {
+	var changes []Change
+	var axis Axis
+	var line int
+	for progress := true; progress; {
+		progress = false
+		for _, q := range b.Views() {
+			for i, row := range q.Rows {
+				for j := 0; j+k < len(row); j++ {
+					idx, num, count := -1, byte(Empty), 0
+					for t, c := range row[j : j+k+1] {
+						if c == Empty {
+							idx = j + t
+						} else if num == Empty || c == num {
+							num = c
+							count++
+						}
+					}
+					if idx >= 0 && count == k {
+						if len(changes) == 0 {
+							axis, line = q.axis(), i+1
+						}
+						changes = append(changes, q.Set(i, idx, flip(num)))
+						progress = true
+					}
+				}
+			}
+		}
+	}
+	b.Unapply(changes)
+
+	return Step{
+		Changes: changes,
+		Diff:    0,
+		Reason:  "Apply simple patterns",
+		axis:    axis,
+		line:    line,
 	}
 }
 
-// remainingNos looks for rows or columns with all numbers of one type filled up.
-// If a row or column has only one number missing, it gives a difficulty of 1. Otherwise,
-// it gives a difficulty of 2.
-func remainingNos(b Board) Step {
+// remainingNos looks for rows or columns with all numbers of one type filled up, as
+// limited by the Balanced rule r. If a row or column has only one number missing, it
+// gives a difficulty of 1. Otherwise, it gives a difficulty of 2.
+func remainingNos(b Board, r Balanced) Step {
 	cheap := Step{Diff: -1}
 	for _, b := range b.Views() {
 		for i, row := range b.Rows {
@@ -88,9 +145,10 @@ func remainingNos(b Board) Step {
 			}
 
 			var repl byte
-			if zero, one := countZeroOne(row); zero == b.Size/2 {
+			limit := r.limit(len(row))
+			if zero, one := b.bits.Rows[i].countZeroOne(); zero == limit {
 				repl = One
-			} else if one == b.Size/2 {
+			} else if one == limit {
 				repl = Zero
 			} else {
 				continue
@@ -111,6 +169,8 @@ func remainingNos(b Board) Step {
 				Reason:  fmt.Sprintf("Only %c's remain in %s %d", repl, b.rowcol(), i+1),
 				Changes: changes,
 				Diff:    diff,
+				axis:    b.axis(),
+				line:    i + 1,
 			}
 			if cheap.Diff < 0 || step.Diff < cheap.Diff {
 				cheap = step
@@ -156,15 +216,21 @@ func completeRow(b Board, rowidx int, fullValidate bool) [][]smallChange {
 	}
 
 	solns := make([][]smallChange, 0)
-	work := make([]smallChange, 0, b.Size)
+	work := make([]smallChange, 0, len(row))
 	valid := b.Validate
 	if !fullValidate {
 		valid = b.smallValidate
 	}
-	zeros, ones := countZeroOne(row)
+	zeros, ones := b.bits.Rows[rowidx].countZeroOne()
+	limit := b.Rules.limit(len(row))
 
+	// For odd rows this undercounts, but it is only a rough cutoff.
 	maxChoices := 15
-	choices := ncr(len(row)-zeros-ones, len(row)/2-zeros)
+	choices := ncr(len(row)-zeros-ones, limit-zeros)
+	if limit == len(row) {
+		// No limit on the numbers of zeros and ones
+		choices = 1 << uint(len(row)-zeros-ones)
+	}
 	if choices > maxChoices {
 		return nil
 	}
@@ -184,7 +250,7 @@ func completeRow(b Board, rowidx int, fullValidate bool) [][]smallChange {
 			return
 		}
 		// Testing triples before placement gives almost no benefit
-		if zeros < b.Size/2 {
+		if zeros < limit {
 			b.Set(rowidx, i, Zero)
 			work = append(work, smallChange{i, Zero})
 			zeros++
@@ -193,7 +259,7 @@ func completeRow(b Board, rowidx int, fullValidate bool) [][]smallChange {
 			b.Set(rowidx, i, Empty)
 			zeros--
 		}
-		if ones < b.Size/2 {
+		if ones < limit {
 			b.Set(rowidx, i, One)
 			work = append(work, smallChange{i, One})
 			ones++
@@ -245,6 +311,8 @@ func completeRows(b Board, fullValidate bool) Step {
 					Reason:  fmt.Sprintf("Only possible arrangement in %s %d", b.rowcol(), rowidx+1),
 					Diff:    baseDiff,
 					Changes: changes,
+					axis:    b.axis(),
+					line:    rowidx + 1,
 				}
 				steps = append(steps, step)
 				continue
@@ -285,11 +353,14 @@ func completeRows(b Board, fullValidate bool) Step {
 					frag = "1 number is"
 				}
 				reason := fmt.Sprintf("Out of %d possibilities in %s %d, %s common", len(solns), b.rowcol(), rowidx+1, frag)
-				zeros, ones := countZeroOne(row)
+				zeros, ones := b.bits.Rows[rowidx].countZeroOne()
+				limit := b.Rules.limit(len(row))
 				step := Step{
 					Changes: inAll,
-					Diff:    baseDiff + intMin(b.Size/2-zeros, b.Size/2-ones),
+					Diff:    baseDiff + intMin(limit-zeros, limit-ones),
 					Reason:  reason,
+					axis:    b.axis(),
+					line:    rowidx + 1,
 				}
 				steps = append(steps, step)
 				continue
@@ -310,24 +381,18 @@ func completeRowsFull(b Board) Step {
 	return completeRows(b, true)
 }
 
-var strats = []func(Board) Step{
-	fixedRepls,
-	remainingNos,
-	completeRowsSmall,
-	completeRowsFull,
-}
-
-var assistStrats = []func(Board) Step {
-	fixedRepls,
-	remainingNos,
-	completeRowsSmall,
-}
-
-func (b Board) solveUsing(strats []func(Board)Step) (Board, []Step, error) {
+func (b Board) solveUsing(ctx context.Context, strats []func(Board)Step) (Board, []Step, error) {
 	b = b.Clone()
 	var steps []Step
 	var err error
 	for i := 0; i < len(strats); {
+		if err = ctx.Err(); err != nil {
+			break
+		}
+		if !b.Validate() {
+			err = SolveErr
+			break
+		}
 		step := strats[i](b)
 		if len(step.Changes) > 0 {
 			b.Apply(step.Changes)
@@ -336,26 +401,58 @@ func (b Board) solveUsing(strats []func(Board)Step) (Board, []Step, error) {
 		} else {
 			i++
 		}
-		if !b.Validate() {
-			err = SolveErr
-			break
-		}
 	}
 	return b, steps, err
 }
 
 // This is the general solver which will solve the board as far as possible using the given strategies.
 // It returns a copy of the board which is as far as it got, the steps it used to get there, and possibly
-// an error, reporting an inconsistency in the board.
+// an error, reporting an inconsistency in the board. The error wraps both SolveErr and a
+// *ConstraintViolation.
 func (b Board) Solve() (Board, []Step, error) {
-	soln, steps, err := b.solveUsing(strats)
+	return b.SolveContext(context.Background())
+}
+
+// SolveContext is like Solve, but gives up when ctx is done, returning the board
+// as far as it got, the steps it took, and ctx.Err().
+func (b Board) SolveContext(ctx context.Context) (Board, []Step, error) {
+	soln, steps, err := b.solveUsing(ctx, b.Rules.strats(false))
+	if err == SolveErr {
+		err = fmt.Errorf("%w: %w", err, soln.Check())
+	}
 	return soln, steps, err
 }
 
+// Grade rates the difficulty of a puzzle as the difficulty of the hardest step Solve
+// takes. It returns -1 if Solve cannot finish the puzzle, and an error if the puzzle
+// is inconsistent.
+func (b Board) Grade() (int, error) {
+	return b.GradeContext(context.Background())
+}
+
+// GradeContext is like Grade, but gives up when ctx is done, returning -1 and
+// ctx.Err().
+func (b Board) GradeContext(ctx context.Context) (int, error) {
+	s, steps, err := b.SolveContext(ctx)
+	if err != nil {
+		return -1, err
+	}
+	if !s.Solved() {
+		return -1, nil
+	}
+	diff := 0
+	for _, step := range steps {
+		if diff < step.Diff {
+			diff = step.Diff
+		}
+	}
+	return diff, nil
+}
+
 // MaybeSolve is used to assist backtracking. It will mutate the board, but also return the changes
 // needed to reverse it. If an inconsistency is caused, it will return false and back off it's changes.
 func (b *Board) MaybeSolve() ([]Change, bool) {
-	q, steps, err := b.solveUsing(assistStrats)
+	q, steps, err := b.solveUsing(context.Background(), b.Rules.strats(true))
 	if err != nil {
 		return nil, false
 	}
@@ -1,6 +1,9 @@
 package binpuz
 
-import "testing"
+import (
+	"context"
+	"testing"
+)
 
 func TestCountEmptySolns(t *testing.T) {
 	tests := []struct {
@@ -17,3 +20,243 @@ func TestCountEmptySolns(t *testing.T) {
 		}
 	}
 }
+
+func TestCountRectSolns(t *testing.T) {
+	tests := []struct {
+		height, width, expect int
+	}{
+		{2, 4, 0},
+		{4, 6, 96},
+		{6, 4, 96},
+	}
+	for _, test := range tests {
+		solns := NewRect(test.height, test.width).CountSolns(-1)
+		if test.expect != solns {
+			t.Errorf("Counted %d solns instead of %d for %d x %d board", solns, test.expect, test.height, test.width)
+		}
+	}
+}
+
+func TestSolveRect(t *testing.T) {
+	p, err := FromString(`0.....
+..1...
+.....0
+1.....`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	solns := p.ListSolns()
+	if len(solns) == 0 {
+		t.Fatalf("No solutions found for\n%s", p)
+	}
+	for _, soln := range solns {
+		if !soln.Solved() || soln.Height != 4 || soln.Width != 6 {
+			t.Errorf("Invalid solution\n%s", soln)
+		}
+	}
+}
+
+func TestCountOddSolns(t *testing.T) {
+	tests := []struct {
+		height, width, expect int
+	}{
+		{1, 1, 2},
+		{3, 3, 84},
+		{3, 4, 72},
+		{4, 5, 96},
+		{5, 5, 8460},
+	}
+	for _, test := range tests {
+		solns := NewRules(test.height, test.width, OddSized).CountSolns(-1)
+		if test.expect != solns {
+			t.Errorf("Counted %d solns instead of %d for odd-sized %d x %d board", solns, test.expect, test.height, test.width)
+		}
+	}
+}
+
+func TestHint(t *testing.T) {
+	p, _ := FromString("....\n0...\n.1..\n0.1.")
+	step, err := p.Hint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if step.Diff != 0 || len(step.Changes) != 1 || step.Changes[0] != (Change{2, 0, One, Empty}) {
+		t.Errorf("Unexpected hint %+v", step)
+	}
+	nudge, err := p.Nudge()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nudge.Reason != "Look at column 1" || len(nudge.Changes) != 0 {
+		t.Errorf("Unexpected nudge %+v", nudge)
+	}
+
+	soln := p.ListSolns()[0]
+	if _, err := soln.Hint(); err != SolvedErr {
+		t.Errorf("Expected SolvedErr for a solved board, got %v", err)
+	}
+}
+
+func TestMistakes(t *testing.T) {
+	puzzle, _ := FromString(`......
+......
+....10
+..1...
+.0.0.0
+...0.0`)
+	b := puzzle.Clone()
+	b.Set(0, 0, One)
+	b.Set(0, 1, One)
+	mistakes, err := b.Mistakes(puzzle)
+	if len(mistakes) != 1 || mistakes[0] != (Change{I: 0, J: 0, B: One}) || err != UnsolvableErr {
+		t.Errorf("Unexpected mistakes %v, %v", mistakes, err)
+	}
+
+	b.Set(0, 0, Zero)
+	mistakes, err = b.Mistakes(puzzle)
+	if len(mistakes) != 0 || err != nil {
+		t.Errorf("Unexpected mistakes %v, %v", mistakes, err)
+	}
+
+	// With few clues there are far too many solutions to list. No one of
+	// these cells is wrong, but together they make a triple.
+	puzzle = New(12)
+	b = puzzle.Clone()
+	b.Set(0, 0, One)
+	b.Set(0, 1, One)
+	b.Set(0, 2, One)
+	mistakes, err = b.Mistakes(puzzle)
+	if len(mistakes) != 0 || err != UnsolvableErr {
+		t.Errorf("Unexpected mistakes %v, %v", mistakes, err)
+	}
+}
+
+func TestListSolns(t *testing.T) {
+	if n := len(New(4).ListSolns()); n != 72 {
+		t.Errorf("Listed %d solns instead of 72 for 4 x 4 board", n)
+	}
+}
+
+func TestCountSolnsContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	if n, err := New(6).CountSolnsContext(ctx, -1); n != 4140 || err != nil {
+		t.Errorf("Counted %d solns (%v) instead of 4140 for 6 x 6 board", n, err)
+	}
+	cancel()
+	if n, err := New(6).CountSolnsContext(ctx, -1); n != 0 || err != context.Canceled {
+		t.Errorf("Counted %d solns (%v) with a cancelled context", n, err)
+	}
+
+	// An already cancelled context stops a search which would never end.
+	solns, err := New(14).ListSolnsContext(ctx)
+	if err != context.Canceled {
+		t.Errorf("Listing solns for 14 x 14 board with a cancelled context gave %v", err)
+	}
+	for _, soln := range solns {
+		if !soln.Solved() {
+			t.Errorf("Invalid partial solution\n%s", soln)
+		}
+	}
+}
+
+func TestSolveContext(t *testing.T) {
+	p, _ := FromString("01.1\n1010\n0.10\n1001")
+	ctx, cancel := context.WithCancel(context.Background())
+	if diff, err := p.GradeContext(ctx); diff < 0 || err != nil {
+		t.Errorf("Graded %d (%v) for a puzzle Solve can finish", diff, err)
+	}
+	cancel()
+	soln, steps, err := p.SolveContext(ctx)
+	if err != context.Canceled || len(steps) != 0 || soln.String() != p.String() {
+		t.Errorf("Solving with a cancelled context gave %d steps (%v)\n%s", len(steps), err, soln)
+	}
+	if diff, err := p.GradeContext(ctx); diff != -1 || err != context.Canceled {
+		t.Errorf("Graded %d (%v) with a cancelled context", diff, err)
+	}
+}
+
+func TestEachSoln(t *testing.T) {
+	n := 0
+	New(6).EachSoln(func(soln Board) bool {
+		if !soln.Solved() {
+			t.Errorf("Invalid solution\n%s", soln)
+		}
+		n++
+		return true
+	})
+	if n != 4140 {
+		t.Errorf("Visited %d solns instead of 4140 for 6 x 6 board", n)
+	}
+
+	seen := make(map[string]bool)
+	for soln := range New(8).Solns() {
+		seen[soln.String()] = true
+		if len(seen) == 10 {
+			break
+		}
+	}
+	if len(seen) != 10 {
+		t.Errorf("Found %d distinct solns instead of 10 for 8 x 8 board", len(seen))
+	}
+}
+
+func TestParallelCount(t *testing.T) {
+	tests := []struct {
+		board        Board
+		limit, count int
+	}{
+		{New(6), -1, 4140},
+		{New(6), 100, 100},
+		{New(6), 0, 1},
+		{New(4), -1, 72},
+		{NewRules(2, 4, NoUnique), -1, 6},
+		{NewRect(2, 4), -1, 0},
+	}
+	defer func(s Search) { DefaultSearch = s }(DefaultSearch)
+	for _, workers := range []int{2, 3, 8} {
+		DefaultSearch = Search{Workers: workers}
+		for _, test := range tests {
+			if n := test.board.CountSolns(test.limit); n != test.count {
+				t.Errorf("Counted %d solns instead of %d with %d workers for\n%s", n, test.count, workers, test.board)
+			}
+		}
+		if New(6).HasUniqueSoln() {
+			t.Errorf("Empty 6 x 6 board has a unique soln with %d workers", workers)
+		}
+	}
+
+	s := Search{Workers: 4, SplitDepth: 20}
+	if n, err := s.CountSolns(context.Background(), New(6), -1); n != 4140 || err != nil {
+		t.Errorf("Counted %d solns (%v) instead of 4140 splitting deeper than the search tree", n, err)
+	}
+}
+
+func TestOrders(t *testing.T) {
+	hard, _ := FromString(difficult12x12)
+	tests := []struct {
+		board Board
+		count int
+	}{
+		{New(4), 72},
+		{NewRules(2, 4, NoUnique), 6},
+		{NewRect(2, 4), 0},
+		{hard, 1},
+	}
+	defer func(s Search) { DefaultSearch = s }(DefaultSearch)
+	for _, order := range []Order{RowMajor, ConstrainedLine, FullestCross, Probe} {
+		DefaultSearch = Search{Order: order}
+		for _, test := range tests {
+			if n := test.board.CountSolns(-1); n != test.count {
+				t.Errorf("Counted %d solns instead of %d in %s order for\n%s", n, test.count, order, test.board)
+			}
+		}
+		s := Search{Workers: 3, Order: order}
+		if n, _ := s.CountSolns(context.Background(), New(4), -1); n != 72 {
+			t.Errorf("Counted %d solns instead of 72 in parallel in %s order", n, order)
+		}
+		solns := hard.ListSolns()
+		if len(solns) != 1 || !solns[0].Solved() {
+			t.Errorf("Listed %d solns in %s order", len(solns), order)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package binpuz
 import (
 	"bytes"
 	"errors"
+	"math/bits"
 	"strings"
 )
 
@@ -15,22 +16,42 @@ const (
 
 // A Board represents a partially complete puzzle.
 type Board struct {
-	// Size must be a multiple of 2
-	Size int
+	// Height and Width must both be multiples of 2, unless the rule
+	// set allows odd sizes
+	Height, Width int
 
-	// Each of these are size*size slices, which must be
-	// kept in sync (the easiest way is through the Set method)
+	// The rules of the puzzle variant this board follows
+	Rules RuleSet
+
+	// Rows is a Height*Width slice and Cols is a Width*Height slice, which
+	// must be kept in sync (the easiest way is through the Set method)
 	Rows, Cols [][]byte
 
+	// The same board packed into bitmasks, kept in sync by Set.
+	bits Bits
+
+	// Which rows and columns break the rules, also kept up to date by Set,
+	// so that validating a board does not need to look at every line.
+	rowTrack, colTrack *lineTrack
+
 	// Are we transposed? (For recording changes)
 	trans bool
 }
 
 // A Change represents a change of one character on the board:
-// replace 'a' at position (i, j) with 'b'
+// replace Prev at position (i, j) with B. A zero Prev is treated as Empty.
 type Change struct {
 	I, J int
 	B    byte
+	Prev byte
+}
+
+// prev returns the byte the change replaced.
+func (c Change) prev() byte {
+	if c.Prev == 0 {
+		return Empty
+	}
+	return c.Prev
 }
 
 // A step is a bundle of actions taken towards solving a puzzle,
@@ -44,48 +65,79 @@ type Step struct {
 
 	// Argument explaining the step.
 	Reason string
+
+	// The row or column the step is about, with line being the index plus
+	// one, or zero if the step is not about a single line.
+	axis Axis
+	line int
 }
 
-// Create a new blank board.
+// Create a new blank square board.
 func New(size int) Board {
-	if size <= 0 || size%2 != 0 {
-		panic(errors.New("Size should be a positive even number!"))
-	}
+	return NewRect(size, size)
+}
+
+// Create a new blank board with the given number of rows and columns.
+func NewRect(height, width int) Board {
+	return NewRules(height, width, Standard)
+}
+
+// Create a new blank board following the given rule set.
+func NewRules(height, width int, rules RuleSet) Board {
+	rules = rules.orStandard()
+	rules.checkDims(height, width)
 
 	// Allocate the board close together in memory, since we will
 	// be working locally a lot.
-	back := make([]byte, size*size*2)
+	back := make([]byte, height*width*2)
 	for i := range back {
 		back[i] = Empty
 	}
-	rows := make([][]byte, size)
+	rows := make([][]byte, height)
 	for i := range rows {
-		rows[i] = back[:size]
-		back = back[size:]
+		rows[i] = back[:width]
+		back = back[width:]
 	}
-	cols := make([][]byte, size)
+	cols := make([][]byte, width)
 	for i := range cols {
-		cols[i] = back[:size]
-		back = back[size:]
+		cols[i] = back[:height]
+		back = back[height:]
 	}
-	return Board{size, rows, cols, false}
+	tracks := new([2]lineTrack)
+	return Board{height, width, rules, rows, cols, NewBits(height, width, rules), &tracks[0], &tracks[1], false}
 }
 
 // Create a board from a string, formatted like "..\n01" or similar (any
-// whitespace to separate lines will do).
+// whitespace to separate lines will do). If the board can be read but breaks one
+// of the rules, it is returned along with a *ConstraintViolation error.
+//
+// FromString also accepts a board written as a single line code (see Code).
 func FromString(s string) (Board, error) {
+	if isCode(s) {
+		return FromCode(s)
+	}
+	return FromStringRules(s, Standard)
+}
+
+// FromStringRules is like FromString, but creates a board following the
+// given rule set.
+func FromStringRules(s string, rules RuleSet) (Board, error) {
 	lines := bytes.Fields([]byte(strings.TrimSpace(s)))
 	if len(lines) == 0 {
 		return Board{}, errors.New("Board must contain data")
 	}
-	size := len(lines)
-	if size%2 != 0 {
-		return Board{}, errors.New("Board size must be even")
+	height, width := len(lines), len(lines[0])
+	if height > MaxSize || width > MaxSize {
+		return Board{}, errors.New("Board height and width must be at most 64")
+	}
+	rules = rules.orStandard()
+	if !rules.validDim(height) || !rules.validDim(width) {
+		return Board{}, errors.New("Board height and width must be even")
 	}
 
-	b := New(size)
+	b := NewRules(height, width, rules)
 	for i, line := range lines {
-		if len(line) != size {
+		if len(line) != width {
 			return Board{}, errors.New("Inconsistent board size")
 		}
 		for j, c := range line {
@@ -95,16 +147,24 @@ func FromString(s string) (Board, error) {
 			b.Set(i, j, c)
 		}
 	}
+	if err := b.Check(); err != nil {
+		return b, err
+	}
 	return b, nil
 }
 
 // Clone makes a copy of the board which shares no data with the original.
 func (b Board) Clone() Board {
-	q := New(b.Size)
-	for i := 0; i < b.Size; i++ {
-		for j := 0; j < b.Size; j++ {
-			q.Set(i, j, b.Rows[i][j])
-		}
+	q := NewRules(b.Height, b.Width, b.Rules)
+	for i, row := range b.Rows {
+		copy(q.Rows[i], row)
+	}
+	for j, col := range b.Cols {
+		copy(q.Cols[j], col)
+	}
+	q.bits = b.bits.Clone()
+	if b.rowTrack != nil {
+		*q.rowTrack, *q.colTrack = *b.rowTrack, *b.colTrack
 	}
 	return q
 }
@@ -117,24 +177,30 @@ func (b Board) Get(i, j int) byte { return b.Rows[i][j] }
 // transposed, and any changes returned by Set should be applied to an
 // un-transposed board.
 func (b Board) Set(i, j int, c byte) Change {
+	change := b.ChangeFor(i, j, c)
 	b.Rows[i][j], b.Cols[j][i] = c, c
-	return b.ChangeFor(i, j, c)
+	row, col := b.bits.Rows[i], b.bits.Cols[j]
+	b.bits.Set(i, j, c)
+	b.rowTrack.update(b.bits.Rows, i, row, b.Width, b.Rules)
+	b.colTrack.update(b.bits.Cols, j, col, b.Height, b.Rules)
+	return change
 }
 
 // ChangeFor returns the same thing as Set does, but does not mutate the board.
 func (b Board) ChangeFor(i, j int, c byte) Change {
+	prev := b.Rows[i][j]
 	if b.trans {
-		return Change{j, i, c}
+		return Change{j, i, c, prev}
 	}
-	return Change{i, j, c}
+	return Change{i, j, c, prev}
 }
 
-// String returns the board as board.Size() lines.
+// String returns the board as board.Height lines.
 func (b Board) String() string {
 	var buf bytes.Buffer
 	for i, row := range b.Rows {
 		buf.Write(row)
-		if i != b.Size-1 {
+		if i != b.Height-1 {
 			buf.WriteByte('\n')
 		}
 	}
@@ -143,13 +209,13 @@ func (b Board) String() string {
 
 // Undo reverts a single change.
 func (b Board) Undo(change Change) {
-	b.Set(change.I, change.J, Empty)
+	b.Set(change.I, change.J, change.prev())
 }
 
-// Unapply reverts a collection of changes.
+// Unapply reverts a collection of changes, in reverse order.
 func (b Board) Unapply(changes []Change) {
-	for _, change := range changes {
-		b.Set(change.I, change.J, Empty)
+	for k := len(changes) - 1; k >= 0; k-- {
+		b.Undo(changes[k])
 	}
 	return
 }
@@ -165,12 +231,8 @@ func (b Board) Apply(changes []Change) {
 // Count returns how many cells in the board are nonempty.
 func (b Board) Count() int {
 	count := 0
-	for _, row := range b.Rows {
-		for _, c := range row {
-			if c != Empty {
-				count++
-			}
-		}
+	for _, l := range b.bits.Rows {
+		count += bits.OnesCount64(l.Filled)
 	}
 	return count
 }
@@ -178,6 +240,8 @@ func (b Board) Count() int {
 // Views returns a [2]Board containing the current board, and the current
 // board transposed.
 func (b Board) Views() [2]Board {
-	t := Board{Size: b.Size, Rows: b.Cols, Cols: b.Rows, trans: !b.trans}
+	t := Board{Height: b.Width, Width: b.Height, Rules: b.Rules, Rows: b.Cols, Cols: b.Rows, trans: !b.trans}
+	t.bits = b.bits.transpose()
+	t.rowTrack, t.colTrack = b.colTrack, b.rowTrack
 	return [2]Board{b, t}
 }
@@ -0,0 +1,28 @@
+package binpuz
+
+import "testing"
+
+func TestUndo(t *testing.T) {
+	p, _ := FromString("01..\n....\n....\n....")
+	change := p.Set(0, 1, Zero)
+	p.Undo(change)
+	if c := p.Get(0, 1); c != One {
+		t.Errorf("Undo left %c instead of restoring 1", c)
+	}
+
+	// Later changes to the same cell must be undone first.
+	changes := []Change{p.Set(0, 2, Zero), p.Set(0, 2, One)}
+	p.Unapply(changes)
+	if c := p.Get(0, 2); c != Empty {
+		t.Errorf("Unapply left %c instead of an empty cell", c)
+	}
+
+	// Changes made without a previous byte clear the cell.
+	p.Undo(Change{I: 0, J: 0, B: Zero})
+	if c := p.Get(0, 0); c != Empty {
+		t.Errorf("Undo left %c instead of an empty cell", c)
+	}
+	if !p.Validate() || p.String() != ".1..\n....\n....\n...." {
+		t.Errorf("Undo left an inconsistent board\n%s", p)
+	}
+}
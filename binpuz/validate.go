@@ -1,97 +1,105 @@
 package binpuz
 
-import "bytes"
-
-// countZeroOne returns the number of zeros and the number of ones
-// in a byte slice.
-func countZeroOne(s []byte) (int, int) {
-	z, o := 0, 0
-	for _, c := range s {
-		if c == Zero {
-			z++
-		} else if c == One {
-			o++
+// Validate returns true if the board obeys all the puzzle constraints,
+// ignoring any Empty cells.
+func (b Board) Validate() bool {
+	if b.rowTrack == nil {
+		return b.bits.Validate()
+	}
+	if b.rowTrack.bad != 0 || b.colTrack.bad != 0 {
+		return false
+	}
+	for _, rule := range b.Rules {
+		if _, ok := rule.(UniqueLines); ok {
+			if b.rowTrack.dups != 0 || b.colTrack.dups != 0 {
+				return false
+			}
+		} else if !rule.CheckBoard(b.bits) {
+			return false
 		}
 	}
-	return z, o
+	return true
 }
 
-// checkThreeAdj returns true if the slice contains three consecutive equal
-// bytes which are not Empty.
-func checkThreeAdj(row []byte) bool {
-	for j := 0; j < len(row)-2; j++ {
-		a, b, c := row[j], row[j+1], row[j+2]
-		if a != Empty && a == b && b == c {
-			return true
-		}
+// A lineTrack follows the rows (or the columns) of a board as it changes.
+type lineTrack struct {
+	// Bit k is set if line k fails the per-line checks of the rules
+	bad uint64
+
+	// The number of pairs of equal full lines
+	dups int
+}
+
+// update brings t up to date after line k of lines, each of length n, has
+// changed from old.
+func (t *lineTrack) update(lines []Line, k int, old Line, n int, rules RuleSet) {
+	if t == nil {
+		return
+	}
+	l, bit := lines[k], uint64(1)<<uint(k)
+	// Rules ignore Empty cells, so emptying cells cannot break a line, and
+	// filling them cannot mend one.
+	switch {
+	case t.bad&bit == 0 && l.within(old):
+	case t.bad&bit != 0 && old.within(l):
+	case rules.checkLine(l, n):
+		t.bad &^= bit
+	default:
+		t.bad |= bit
+	}
+	full := fullMask(n)
+	if old.Filled == full && t.dups > 0 {
+		t.dups -= equalLines(lines, k, old)
+	}
+	if l.Filled == full {
+		t.dups += equalLines(lines, k, l)
 	}
-	return false
 }
 
-// Validate returns true if the board obeys all the puzzle constraints,
-// ignoring any Empty cells.
-func (b Board) Validate() bool {
-	for _, grid := range [][][]byte{b.Rows, b.Cols} {
-		// Leave the capacity as a constant here to generate no garbage.
-		fulls := make([][]byte, 0, 12)
-		for _, row := range grid {
-			// Three equal adjacent numbers
-			if checkThreeAdj(row) {
-				return false
-			}
+// equalLines returns the number of lines other than line k which equal l.
+func equalLines(lines []Line, k int, l Line) int {
+	n := 0
+	for m, other := range lines {
+		if m != k && other == l {
+			n++
+		}
+	}
+	return n
+}
 
-			// Numbers of ones and zeros
-			zeros, ones := countZeroOne(row)
-			if zeros > b.Size/2 || ones > b.Size/2 {
-				return false
-			}
+// Check returns nil if the board obeys all the puzzle constraints, ignoring any
+// Empty cells. Otherwise it returns a *ConstraintViolation describing the first
+// broken constraint it finds.
+func (b Board) Check() error {
+	if b.Validate() {
+		return nil
+	}
+	if vs := b.bits.violations(); len(vs) > 0 {
+		return vs[0]
+	}
+	return &ConstraintViolation{Kind: Other, Index: -1}
+}
 
-			// Uniqueness of rows (ignore rows which are not full)
-			if zeros+ones != b.Size {
-				continue
-			}
-			for _, full := range fulls {
-				if bytes.Equal(row, full) {
-					return false
-				}
-			}
-			fulls = append(fulls, row)
-		}
+// Conflicts returns every violation of the puzzle constraints on the board,
+// ignoring any Empty cells. It returns nil for a valid board.
+func (b Board) Conflicts() []*ConstraintViolation {
+	if b.Validate() {
+		return nil
 	}
-	return true
+	return b.bits.violations()
 }
 
 // Solved returns true if the board is both valid and full (no Empty characters).
 func (b Board) Solved() bool {
-	if !b.Validate() {
-		return false
-	}
-	for _, row := range b.Rows {
-		for _, c := range row {
-			if c == Empty {
-				return false
-			}
-		}
-	}
-	return true
+	return b.Validate() && b.bits.Full()
 }
 
-// smallValidate ignores the equal rows/columns constraint. This is for use in
+// smallValidate only checks the rules one line at a time, which ignores
+// constraints such as the equal rows/columns one. This is for use in
 // difficulty grading.
 func (b Board) smallValidate() bool {
-	for _, grid := range [][][]byte{b.Rows, b.Cols} {
-		for _, row := range grid {
-			// Three equal adjacent numbers
-			if checkThreeAdj(row) {
-				return false
-			}
-
-			// Numbers of ones and zeros
-			zeros, ones := countZeroOne(row)
-			if zeros > b.Size/2 || ones > b.Size/2 {
-				return false
-			}
-		}
+	if b.rowTrack == nil {
+		return b.bits.validLines()
 	}
-	return true
+	return b.rowTrack.bad == 0 && b.colTrack.bad == 0
 }
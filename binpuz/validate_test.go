@@ -0,0 +1,99 @@
+package binpuz
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		board string
+		kind  ViolationKind
+		axis  Axis
+		index int
+		cells int
+	}{
+		{"0.00\n....\n....\n....", Count, RowAxis, 0, 3},
+		{"....\n.1..\n.1..\n.1..", Triple, ColAxis, 1, 3},
+		{"0...\n....\n0...\n0...", Count, ColAxis, 0, 3},
+		{"0110\n....\n0110\n....", Duplicate, RowAxis, 2, 8},
+	}
+	for _, test := range tests {
+		_, err := FromString(test.board)
+		var cv *ConstraintViolation
+		if !errors.As(err, &cv) {
+			t.Errorf("Expected a violation for board\n%s\ngot %v", test.board, err)
+			continue
+		}
+		if cv.Kind != test.kind || cv.Axis != test.axis || cv.Index != test.index || (test.cells > 0 && len(cv.Cells) != test.cells) {
+			t.Errorf("Unexpected violation %v (%v, %v %d, %d cells) for board\n%s", cv, cv.Kind, cv.Axis, cv.Index, len(cv.Cells), test.board)
+		}
+	}
+}
+
+func TestSolveViolation(t *testing.T) {
+	// Solving this forces the last two rows to be equal.
+	p, err := FromString("0...\n0...\n.1.0\n.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = p.Solve()
+	var cv *ConstraintViolation
+	if !errors.Is(err, SolveErr) || !errors.As(err, &cv) {
+		t.Errorf("Expected a wrapped violation, got %v", err)
+	}
+}
+
+func TestConflicts(t *testing.T) {
+	p, _ := FromString("0000\n.1..\n.1..\n0110")
+	kinds := map[ViolationKind]int{}
+	for _, cv := range p.Conflicts() {
+		kinds[cv.Kind]++
+	}
+	if kinds[Triple] != 3 || kinds[Count] != 2 || len(kinds) != 2 {
+		t.Errorf("Unexpected conflicts %v", p.Conflicts())
+	}
+	if q := New(4); q.Conflicts() != nil {
+		t.Errorf("Expected no conflicts on an empty board")
+	}
+}
+
+// silent forbids a One in the top left cell, but never explains why.
+type silent struct{}
+
+func (silent) CheckLine(l Line, n int) bool { return true }
+func (silent) CheckBoard(q Bits) bool       { return q.Get(0, 0) != One }
+func (silent) Explain(q Bits) []*ConstraintViolation {
+	return nil
+}
+
+func TestUnexplainedViolation(t *testing.T) {
+	p := NewRules(4, 4, RuleSet{MaxRun(2), silent{}})
+	p.Set(0, 0, One)
+	var cv *ConstraintViolation
+	if err := p.Check(); !errors.As(err, &cv) || cv.Kind != Other || cv.Rule != (silent{}) {
+		t.Errorf("Expected a violation of the silent rule, got %v", err)
+	}
+	if len(p.Conflicts()) != 1 {
+		t.Errorf("Expected one conflict, got %v", p.Conflicts())
+	}
+}
+
+func TestIncrementalValidate(t *testing.T) {
+	// Random changes, through both views and clones, must leave the tracked
+	// validity matching a full check of the packed board.
+	r := rand.New(rand.NewSource(1))
+	for _, b := range []Board{NewRect(4, 4), NewRules(4, 4, NoUnique), NewRules(5, 4, OddSized)} {
+		for k := 0; k < 2000; k++ {
+			q := b.Views()[r.Intn(2)]
+			q.Set(r.Intn(q.Height), r.Intn(q.Width), []byte{Empty, Zero, One}[r.Intn(3)])
+			if k%100 == 0 {
+				b = b.Clone()
+			}
+			if b.Validate() != b.bits.Validate() || b.smallValidate() != b.bits.validLines() {
+				t.Fatalf("Tracked validity %v differs from full check for\n%s", b.Validate(), b)
+			}
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package binpuz
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// An Axis says whether a line of the board is a row or a column.
+type Axis int
+
+const (
+	RowAxis Axis = iota
+	ColAxis
+)
+
+func (a Axis) String() string {
+	if a == ColAxis {
+		return "column"
+	}
+	return "row"
+}
+
+// A ViolationKind says which constraint of the puzzle a board breaks.
+type ViolationKind int
+
+const (
+	// Triple is a run of too many equal adjacent numbers in a line (three,
+	// under the standard rules).
+	Triple ViolationKind = iota
+
+	// Count is a line holding too many of one number.
+	Count
+
+	// Duplicate is a pair of equal full lines.
+	Duplicate
+
+	// Diagonal is a run of too many equal numbers along a diagonal.
+	Diagonal
+
+	// Other is any violation of a rule outside this package.
+	Other
+)
+
+func (k ViolationKind) String() string {
+	switch k {
+	case Triple:
+		return "triple"
+	case Count:
+		return "count"
+	case Duplicate:
+		return "duplicate"
+	case Diagonal:
+		return "diagonal"
+	}
+	return "other"
+}
+
+// A ConstraintViolation describes one place where a board breaks one of its
+// rules.
+type ConstraintViolation struct {
+	Kind ViolationKind
+
+	// The rule which is broken
+	Rule Rule
+
+	// The line the violation is in, or Index -1 if it is not in a single
+	// row or column. For Duplicate violations, First is the index of the
+	// earlier equal line.
+	Axis         Axis
+	Index, First int
+
+	// The offending cells, holding their current numbers
+	Cells []Change
+}
+
+func (v *ConstraintViolation) Error() string {
+	switch v.Kind {
+	case Triple:
+		return fmt.Sprintf("%d adjacent %c's in %s %d", len(v.Cells), v.Cells[0].B, v.Axis, v.Index+1)
+	case Count:
+		return fmt.Sprintf("Too many %c's in %s %d", v.Cells[0].B, v.Axis, v.Index+1)
+	case Duplicate:
+		name := "Rows"
+		if v.Axis == ColAxis {
+			name = "Columns"
+		}
+		return fmt.Sprintf("%s %d and %d are equal", name, v.First+1, v.Index+1)
+	case Diagonal:
+		c := v.Cells[0]
+		return fmt.Sprintf("%d diagonally adjacent %c's from row %d, column %d", len(v.Cells), c.B, c.I+1, c.J+1)
+	}
+	if v.Index < 0 {
+		return fmt.Sprintf("Board breaks rule %v", v.Rule)
+	}
+	return fmt.Sprintf("%s %d breaks rule %v", v.Axis, v.Index+1, v.Rule)
+}
+
+// An Explainer is a Rule which can say exactly where a board breaks it.
+// Explain returns every violation of the rule on the board.
+type Explainer interface {
+	Rule
+	Explain(q Bits) []*ConstraintViolation
+}
+
+// axis returns the lines of q along the given axis, and their length.
+func (q Bits) axis(a Axis) ([]Line, int) {
+	if a == ColAxis {
+		return q.Cols, q.Height
+	}
+	return q.Rows, q.Width
+}
+
+// cells returns the cells selected by mask m in line idx along the given
+// axis, as Changes holding their current numbers.
+func (l Line) cells(a Axis, idx int, m uint64) []Change {
+	var cells []Change
+	for ; m != 0; m &= m - 1 {
+		j := bits.TrailingZeros64(m)
+		if a == ColAxis {
+			cells = append(cells, Change{I: j, J: idx, B: l.Get(j)})
+		} else {
+			cells = append(cells, Change{I: idx, J: j, B: l.Get(j)})
+		}
+	}
+	return cells
+}
+
+// explain returns every violation of rule on q. Rules which are not
+// Explainers are reported line by line, as violations of kind Other.
+func explain(rule Rule, q Bits) []*ConstraintViolation {
+	if e, ok := rule.(Explainer); ok {
+		return e.Explain(q)
+	}
+	var vs []*ConstraintViolation
+	for _, a := range []Axis{RowAxis, ColAxis} {
+		lines, n := q.axis(a)
+		for i, l := range lines {
+			if !rule.CheckLine(l, n) {
+				vs = append(vs, &ConstraintViolation{Kind: Other, Rule: rule, Axis: a, Index: i, Cells: l.cells(a, i, l.Filled)})
+			}
+		}
+	}
+	if !rule.CheckBoard(q) {
+		vs = append(vs, &ConstraintViolation{Kind: Other, Rule: rule, Index: -1})
+	}
+	return vs
+}
+
+// violations returns every violation of the rules on q. An Explainer which is
+// broken but explains nothing is reported as a violation of kind Other.
+func (q Bits) violations() []*ConstraintViolation {
+	var vs []*ConstraintViolation
+	for _, rule := range q.Rules {
+		found := explain(rule, q)
+		if len(found) == 0 && !(Bits{q.Height, q.Width, RuleSet{rule}, q.Rows, q.Cols}).Validate() {
+			found = []*ConstraintViolation{{Kind: Other, Rule: rule, Index: -1}}
+		}
+		vs = append(vs, found...)
+	}
+	return vs
+}
+
+func (r MaxRun) Explain(q Bits) []*ConstraintViolation {
+	var vs []*ConstraintViolation
+	for _, a := range []Axis{RowAxis, ColAxis} {
+		lines, _ := q.axis(a)
+		for i, l := range lines {
+			for _, m := range []uint64{l.zeros(), l.Ones} {
+				for starts := r.run(m); starts != 0; starts &= starts - 1 {
+					run := fullMask(int(r)+1) << uint(bits.TrailingZeros64(starts))
+					vs = append(vs, &ConstraintViolation{Kind: Triple, Rule: r, Axis: a, Index: i, Cells: l.cells(a, i, run)})
+				}
+			}
+		}
+	}
+	return vs
+}
+
+func (r Balanced) Explain(q Bits) []*ConstraintViolation {
+	var vs []*ConstraintViolation
+	for _, a := range []Axis{RowAxis, ColAxis} {
+		lines, n := q.axis(a)
+		for i, l := range lines {
+			for _, m := range []uint64{l.zeros(), l.Ones} {
+				if bits.OnesCount64(m) > r.limit(n) {
+					vs = append(vs, &ConstraintViolation{Kind: Count, Rule: r, Axis: a, Index: i, Cells: l.cells(a, i, m)})
+				}
+			}
+		}
+	}
+	return vs
+}
+
+func (r UniqueLines) Explain(q Bits) []*ConstraintViolation {
+	var vs []*ConstraintViolation
+	for _, a := range []Axis{RowAxis, ColAxis} {
+		lines, n := q.axis(a)
+		full := fullMask(n)
+		for i, l := range lines {
+			if l.Filled != full {
+				continue
+			}
+			for k, prev := range lines[:i] {
+				if prev == l {
+					cells := append(prev.cells(a, k, full), l.cells(a, i, full)...)
+					vs = append(vs, &ConstraintViolation{Kind: Duplicate, Rule: r, Axis: a, Index: i, First: k, Cells: cells})
+				}
+			}
+		}
+	}
+	return vs
+}
+
+func (r MaxDiagonalRun) Explain(q Bits) []*ConstraintViolation {
+	var vs []*ConstraintViolation
+	k := int(r)
+	for i := 0; i+k < q.Height; i++ {
+		for _, ones := range []bool{false, true} {
+			down, up := q.Rows[i].mask(ones), q.Rows[i].mask(ones)
+			for t := 1; t <= k; t++ {
+				m := q.Rows[i+t].mask(ones)
+				down &= m >> uint(t)
+				up &= m << uint(t)
+			}
+			for dir, starts := range []uint64{down, up} {
+				for ; starts != 0; starts &= starts - 1 {
+					j := bits.TrailingZeros64(starts)
+					var cells []Change
+					for t := 0; t <= k; t++ {
+						jt := j + t
+						if dir == 1 {
+							jt = j - t
+						}
+						cells = append(cells, Change{I: i + t, J: jt, B: q.Get(i+t, jt)})
+					}
+					vs = append(vs, &ConstraintViolation{Kind: Diagonal, Rule: r, Index: -1, Cells: cells})
+				}
+			}
+		}
+	}
+	return vs
+}